@@ -0,0 +1,226 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// exampleConfig is the annotated default configuration written by config init
+//
+//go:embed config.example.yaml
+var exampleConfig []byte
+
+// configUsage describes the config subcommands
+const configUsage = `Usage: mcp-poke config <command> [options] [path]
+
+Commands:
+  init        Write the annotated default configuration (-force overwrites an existing file)
+  show        Print the effective configuration: defaults, the system, user and project files,
+              MCP_POKE_* variables and the -verbose and -dry-run flags (-sources lists where
+              each setting was set)
+  path        Print the configuration files and where the user file is looked up, in order
+  validate    Check the configuration files, reporting problems with their line and column
+
+The path replaces the platform-specific user configuration file.
+`
+
+// redacted replaces secrets in config show
+const redacted = "<redacted>"
+
+// runConfigCommand runs "mcp-poke config <command>" and returns the exit code
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, configUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "init":
+		return initConfig(args[1:], stdout, stderr)
+	case "show":
+		return showConfig(args[1:], stdout, stderr)
+	case "path":
+		return printConfigPaths(args[1:], stdout, stderr)
+	case "validate":
+		return validateConfig(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, configUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown config command: %s\n\n%s", args[0], configUsage)
+		return 2
+	}
+}
+
+// parseConfigArgs parses the options of a config command and returns the
+// configuration file it applies to
+func parseConfigArgs(flags *flag.FlagSet, args []string, stderr io.Writer) (string, bool) {
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return "", false
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprint(stderr, configUsage)
+		return "", false
+	}
+
+	path := flags.Arg(0)
+	if path == "" {
+		path = config.GetConfigPath()
+	}
+	return path, true
+}
+
+// initConfig writes the annotated default configuration, refusing to replace
+// an existing file unless forced
+func initConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("config init", flag.ContinueOnError)
+	force := flags.Bool("force", false, "Overwrite an existing configuration file")
+	path, ok := parseConfigArgs(flags, args, stderr)
+	if !ok {
+		return 2
+	}
+
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Fprintf(stderr, "%s already exists, use -force to overwrite it\n", path)
+		return 1
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(stderr, "failed to create config directory: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, exampleConfig, 0644); err != nil {
+		fmt.Fprintf(stderr, "failed to write config file: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Wrote %s\n", path)
+	return 0
+}
+
+// showConfig prints the configuration the server would run with, or where
+// each setting was set, with tokens and webhook headers redacted
+func showConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("config show", flag.ContinueOnError)
+	verbose := flags.Bool("verbose", false, "Apply the -verbose server flag")
+	dryRun := flags.Bool("dry-run", false, "Apply the -dry-run server flag")
+	sources := flags.Bool("sources", false, "List every setting with where it was set instead")
+	path, ok := parseConfigArgs(flags, args, stderr)
+	if !ok {
+		return 2
+	}
+
+	layers := config.Layers(path)
+	cfg, err := loadConfig(layers, *verbose, *dryRun)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	for i := range cfg.Server.Auth.Tokens {
+		cfg.Server.Auth.Tokens[i].Token = redacted
+	}
+	for _, backend := range cfg.Notification.Backends {
+		for name := range backend.Webhook.Headers {
+			backend.Webhook.Headers[name] = redacted
+		}
+	}
+
+	if *sources {
+		for _, setting := range cfg.Settings() {
+			fmt.Fprintf(stdout, "%s: %s  # %s\n", setting.Path, setting.Value, setting.Source)
+		}
+		return 0
+	}
+
+	for _, layer := range layers {
+		fmt.Fprintf(stdout, "# %s: %s\n", layer.Name, configFileStatus(layer.Path))
+	}
+	encoder := yaml.NewEncoder(stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		fmt.Fprintf(stderr, "failed to encode configuration: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printConfigPaths prints the configuration files merged in order and the
+// locations the user file is looked up in
+func printConfigPaths(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("config path", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return 2
+	}
+
+	fmt.Fprintln(stdout, "Configuration files, later ones overriding earlier ones:")
+	for _, layer := range config.Layers(config.GetConfigPath()) {
+		fmt.Fprintf(stdout, "  %s: %s\n", layer.Name, configFileStatus(layer.Path))
+	}
+	fmt.Fprintf(stdout, "  then the %s* variables and the -verbose and -dry-run flags\n\n", config.EnvPrefix)
+
+	fmt.Fprintln(stdout, "User configuration file, in order of precedence:")
+	fmt.Fprintln(stdout, "  1. -config flag of the server")
+	for i, p := range config.ConfigPaths() {
+		location := p.Path
+		if location == "" {
+			location = "not set"
+		}
+		fmt.Fprintf(stdout, "  %d. %s: %s\n", i+2, p.Source, location)
+	}
+	fmt.Fprintf(stdout, "Using %s\n", configFileStatus(config.GetConfigPath()))
+	return 0
+}
+
+// configFileStatus describes path and whether it exists
+func configFileStatus(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path + " (not found)"
+	}
+	return path
+}
+
+// validateConfig loads the configuration files and the environment like the
+// server does and reports whether they are valid
+func validateConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	path, ok := parseConfigArgs(flags, args, stderr)
+	if !ok {
+		return 2
+	}
+
+	layers := config.Layers(path)
+	cfg, err := config.LoadLayers(layers)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	// The token file is otherwise only read once a network transport starts
+	if _, err := cfg.Server.Auth.LoadTokens(); err != nil {
+		source := cfg.Source("server.auth.token_file")
+		fmt.Fprintf(stderr, "invalid configuration in %s: server.auth.token_file: %v\n", source, err)
+		return 1
+	}
+
+	found := false
+	for _, layer := range layers {
+		if _, err := os.Stat(layer.Path); err == nil {
+			fmt.Fprintf(stdout, "%s is valid\n", layer.Path)
+			found = true
+		}
+	}
+	if !found {
+		fmt.Fprintf(stdout, "No configuration file exists, the defaults and %s* variables are valid\n", config.EnvPrefix)
+	}
+	return 0
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// runConfig runs a config command and returns its exit code and output
+func runConfig(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := runConfigCommand(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// useConfigHome points the platform-specific configuration file to a temporary directory
+func useConfigHome(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("APPDATA", dir)
+	return config.GetConfigPath()
+}
+
+func TestConfigInit(t *testing.T) {
+	path := useConfigHome(t)
+
+	code, stdout, stderr := runConfig(t, "init")
+	if code != 0 {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, path) {
+		t.Errorf("Expected the written path in the output, got %q", stdout)
+	}
+
+	// The annotated configuration loads and is the default one
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load the written configuration: %v", err)
+	}
+	if cfg.Notification.DefaultLevel != config.DefaultConfig().Notification.DefaultLevel {
+		t.Errorf("Expected the default level, got %s", cfg.Notification.DefaultLevel)
+	}
+
+	// An existing file is only replaced when forced
+	if err := os.WriteFile(path, []byte("notification:\n  verbose: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if code, _, _ := runConfig(t, "init"); code == 0 {
+		t.Error("Expected init to refuse overwriting an existing file")
+	}
+	if code, _, stderr := runConfig(t, "init", "-force"); code != 0 {
+		t.Errorf("Expected -force to overwrite the file, got %d: %s", code, stderr)
+	}
+}
+
+func TestConfigShow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+notification:
+  default_level: warning
+  backends:
+    - name: hook
+      type: webhook
+      webhook:
+        url: https://example.com/hook
+        headers:
+          Authorization: Bearer secret-header
+server:
+  auth:
+    tokens:
+      - name: ci
+        token: secret-token
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	code, stdout, stderr := runConfig(t, "show", "-dry-run", path)
+	if code != 0 {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+
+	for _, expected := range []string{
+		"default_level: warning", // from the file
+		"dry_run: true",          // from the flag
+		"action_timeout: 120",    // from the defaults
+	} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("Expected %q in the output, got:\n%s", expected, stdout)
+		}
+	}
+	for _, secret := range []string{"secret-token", "secret-header"} {
+		if strings.Contains(stdout, secret) {
+			t.Errorf("Expected %s to be redacted, got:\n%s", secret, stdout)
+		}
+	}
+}
+
+func TestConfigShow_Sources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("notification:\n  default_level: warning\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("MCP_POKE_ACTION_TIMEOUT", "30")
+
+	code, stdout, stderr := runConfig(t, "show", "-sources", "-verbose", path)
+	if code != 0 {
+		t.Fatalf("Expected success, got %d: %s", code, stderr)
+	}
+
+	for _, expected := range []string{
+		"notification.default_level: warning  # " + path + ":2",
+		"notification.action_timeout: 30  # $MCP_POKE_ACTION_TIMEOUT",
+		"notification.verbose: true  # -verbose flag",
+		"notification.dry_run: false  # default",
+	} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("Expected %q in the output, got:\n%s", expected, stdout)
+		}
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	path := useConfigHome(t)
+
+	code, stdout, _ := runConfig(t, "path")
+	if code != 0 {
+		t.Fatalf("Expected success, got %d", code)
+	}
+	if !strings.Contains(stdout, "Using "+path+" (not found)") {
+		t.Errorf("Expected the path in use, got:\n%s", stdout)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := os.WriteFile(path, []byte("notification:\n  verbose: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if code, stdout, _ := runConfig(t, "validate", path); code != 0 || !strings.Contains(stdout, "is valid") {
+		t.Errorf("Expected a valid configuration, got %d: %s", code, stdout)
+	}
+
+	if err := os.WriteFile(path, []byte("notification:\n  verbos: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	code, _, stderr := runConfig(t, "validate", path)
+	if code != 1 || !strings.Contains(stderr, "line 2, column 3") {
+		t.Errorf("Expected the location of the unknown key, got %d: %s", code, stderr)
+	}
+}
+
+func TestConfigCommand_Unknown(t *testing.T) {
+	if code, _, stderr := runConfig(t, "frobnicate"); code != 2 || !strings.Contains(stderr, "unknown config command") {
+		t.Errorf("Expected usage error, got %d: %s", code, stderr)
+	}
+}
@@ -1,10 +1,20 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,35 +22,436 @@ import (
 // Config represents the application configuration
 type Config struct {
 	Notification NotificationConfig `yaml:"notification"`
+	Server       ServerConfig       `yaml:"server"`
+
+	// sources maps the paths of the settings that are not defaults to where they were set
+	sources map[string]string
+}
+
+// ServerConfig contains settings of the network transports
+type ServerConfig struct {
+	Auth AuthConfig `yaml:"auth"`
+}
+
+// AuthConfig lists the bearer tokens accepted by the network transports.
+// Authentication is required as soon as any token is configured
+type AuthConfig struct {
+	Tokens []Token `yaml:"tokens"`
+	// TokenFile holds one "name token" pair per line; blank lines and lines starting with # are ignored
+	TokenFile string `yaml:"token_file"`
+	// AllowUnauthenticated serves addresses other than loopback without tokens
+	AllowUnauthenticated bool `yaml:"allow_unauthenticated"`
+}
+
+// Token is a bearer token and the name notifications sent with it are shown under
+type Token struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
 }
 
 // NotificationConfig contains notification-specific settings
 type NotificationConfig struct {
-	DryRun   bool              `yaml:"dry_run"`
-	Verbose  bool              `yaml:"verbose"`
-	Template Template          `yaml:"template"`
-	Levels   map[string]Level  `yaml:"levels"`
+	DryRun  bool   `yaml:"dry_run"`
+	Verbose bool   `yaml:"verbose"`
+	Backend string `yaml:"backend"`
+	// Fallback is the chain tried in order until one path delivers the
+	// notification: desktop, terminal and file
+	Fallback []string         `yaml:"fallback"`
+	Terminal TerminalConfig   `yaml:"terminal"`
+	Template Template         `yaml:"template"`
+	Levels   map[string]Level `yaml:"levels"`
+	// DefaultLevel is used when the agent does not specify a level
+	DefaultLevel string `yaml:"default_level"`
+	// ActionTimeout is how long, in seconds, poke waits for the user to pick an action
+	ActionTimeout int             `yaml:"action_timeout"`
+	History       HistoryConfig   `yaml:"history"`
+	RateLimit     RateLimitConfig `yaml:"rate_limit"`
+	Batch         BatchConfig     `yaml:"batch"`
+	Schedule      ScheduleConfig  `yaml:"schedule"`
+	Sound         SoundConfig     `yaml:"sound"`
+	LogFile       LogFileConfig   `yaml:"log_file"`
+	// Backends lists where notifications are delivered; empty means the desktop only
+	Backends []BackendConfig `yaml:"backends"`
+	// Routes select the backends for a notification; the first matching route wins
+	Routes []Route `yaml:"routes"`
+}
+
+// BackendConfig is a named notification backend. Only the section matching Type is used
+type BackendConfig struct {
+	Name     string         `yaml:"name"`
+	Type     string         `yaml:"type"`
+	Webhook  WebhookConfig  `yaml:"webhook"`
+	Command  CommandConfig  `yaml:"command"`
+	File     FileConfig     `yaml:"file"`
+	Terminal TerminalConfig `yaml:"terminal"`
+}
+
+// FileConfig configures a rotating JSON lines notification log
+type FileConfig struct {
+	// Path defaults to notifications.jsonl in the platform-specific state directory
+	Path string `yaml:"path"`
+	// MaxSizeMB rotates the file before it grows past this size (0 uses the default of 10)
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxBackups is how many rotated files are kept (0 uses the default of 3)
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// LogFilePath returns the configured log file, or the default one in the state directory
+func (f FileConfig) LogFilePath() string {
+	if f.Path != "" {
+		return f.Path
+	}
+	return filepath.Join(GetStateDir(), "notifications.jsonl")
+}
+
+// validate checks that the rotation limits are usable
+func (f FileConfig) validate() error {
+	if f.MaxSizeMB < 0 {
+		return fieldErrorf("max_size_mb", "cannot be negative")
+	}
+	if f.MaxBackups < 0 {
+		return fieldErrorf("max_backups", "cannot be negative")
+	}
+	return nil
+}
+
+// LogFileConfig controls logging every notification and its delivery result to a file
+type LogFileConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	FileConfig `yaml:",inline"`
+}
+
+// CommandConfig configures a backend running a program for every notification
+type CommandConfig struct {
+	// Args is the program and its arguments. Each one is a text/template with
+	// .Title, .Message, .Level, .Urgency, .Icon, .Workspace and .Client
+	Args []string `yaml:"args"`
+	// Timeout is in seconds (0 uses the default of 10)
+	Timeout int `yaml:"timeout"`
+	// MaxConcurrent caps how many instances run at once (0 uses the default of 4)
+	MaxConcurrent int `yaml:"max_concurrent"`
+}
+
+// validate checks that there is a program to run and that the arguments parse
+func (c CommandConfig) validate() error {
+	if len(c.Args) == 0 || c.Args[0] == "" {
+		return fieldErrorf("args", "must start with the program to run")
+	}
+	for i, arg := range c.Args {
+		if _, err := template.New("arg").Parse(arg); err != nil {
+			return fieldError(fmt.Errorf("invalid template: %w", err), "args", index(i))
+		}
+	}
+	if c.Timeout < 0 {
+		return fieldErrorf("timeout", "cannot be negative")
+	}
+	if c.MaxConcurrent < 0 {
+		return fieldErrorf("max_concurrent", "cannot be negative")
+	}
+	return nil
+}
+
+// WebhookConfig configures a backend posting notifications to a URL
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Format is the payload format: slack, discord, ntfy, gotify or json (default)
+	Format string `yaml:"format"`
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string `yaml:"headers"`
+	// Timeout is in seconds per attempt (0 uses the default of 10)
+	Timeout int `yaml:"timeout"`
+	// Retries is how many times a failed request is retried, with exponential backoff
+	Retries int `yaml:"retries"`
+}
+
+// Supported webhook payload formats
+const (
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+	WebhookFormatNtfy    = "ntfy"
+	WebhookFormatGotify  = "gotify"
+	WebhookFormatJSON    = "json"
+)
+
+// validate checks that the webhook can be posted to
+func (w WebhookConfig) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fieldErrorf("url", "must be an http or https URL, got %q", w.URL)
+	}
+	switch w.Format {
+	case "", WebhookFormatSlack, WebhookFormatDiscord, WebhookFormatNtfy, WebhookFormatGotify, WebhookFormatJSON:
+	default:
+		return fieldErrorf("format", "unknown format: %s (must be one of: slack, discord, ntfy, gotify, json)", w.Format)
+	}
+	if w.Timeout < 0 {
+		return fieldErrorf("timeout", "cannot be negative")
+	}
+	if w.Retries < 0 {
+		return fieldErrorf("retries", "cannot be negative")
+	}
+	return nil
+}
+
+// Route sends notifications matching all of its conditions to Backends.
+// Empty conditions match everything
+type Route struct {
+	Levels []string `yaml:"levels"`
+	// Workspaces are path.Match patterns such as "projects/*"
+	Workspaces []string `yaml:"workspaces"`
+	Backends   []string `yaml:"backends"`
+}
+
+// Matches reports whether a notification with level and workspace takes this route
+func (r Route) Matches(level, workspace string) bool {
+	if len(r.Levels) > 0 && !slices.Contains(r.Levels, level) {
+		return false
+	}
+	if len(r.Workspaces) == 0 {
+		return true
+	}
+	for _, pattern := range r.Workspaces {
+		if ok, _ := path.Match(pattern, workspace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Supported backend types
+const (
+	BackendTypeDesktop = "desktop"
+	BackendTypeWebhook = "webhook"
+	BackendTypeCommand = "command"
+	BackendTypeFile    = "file"
+	// BackendTypeTerminal writes escape sequences to a terminal
+	BackendTypeTerminal = "terminal"
+)
+
+// fallbackPaths lists what the fallback chain can be made of
+var fallbackPaths = []string{BackendTypeDesktop, BackendTypeTerminal, BackendTypeFile}
+
+// Escape sequences the terminal notifier can emit
+const (
+	// TerminalSequenceOSC9 is understood by iTerm2, Windows Terminal, WezTerm, Ghostty and others
+	TerminalSequenceOSC9 = "osc9"
+	// TerminalSequenceOSC777 is understood by urxvt, foot, Konsole, Ghostty and others
+	TerminalSequenceOSC777 = "osc777"
+	// TerminalSequenceOSC99 is kitty's notification protocol, with separate title and urgency
+	TerminalSequenceOSC99 = "osc99"
+	// TerminalSequenceBell just rings the bell
+	TerminalSequenceBell = "bell"
+)
+
+// When to wrap escape sequences for tmux
+const (
+	// TmuxAuto wraps them when running inside tmux ($TMUX is set)
+	TmuxAuto   = "auto"
+	TmuxAlways = "always"
+	TmuxNever  = "never"
+)
+
+// TerminalConfig configures notifications written to the terminal as escape sequences
+type TerminalConfig struct {
+	// Sequence is the escape sequence to emit, osc9 by default
+	Sequence string `yaml:"sequence"`
+	// TTY is the terminal device to write to. By default the controlling
+	// terminal is used, or the parent process's terminal if there is none
+	TTY string `yaml:"tty"`
+	// Tmux controls the tmux passthrough wrapping, which needs
+	// "set -g allow-passthrough on" in tmux 3.3 or later (default auto)
+	Tmux string `yaml:"tmux"`
 }
 
-// Template contains message template configuration
+// validate checks that the sequence and tmux mode are known
+func (t TerminalConfig) validate() error {
+	switch t.Sequence {
+	case "", TerminalSequenceOSC9, TerminalSequenceOSC777, TerminalSequenceOSC99, TerminalSequenceBell:
+	default:
+		return fieldErrorf("sequence", "unknown sequence: %s (must be one of: osc9, osc777, osc99, bell)", t.Sequence)
+	}
+	switch t.Tmux {
+	case "", TmuxAuto, TmuxAlways, TmuxNever:
+	default:
+		return fieldErrorf("tmux", "unknown tmux mode: %s (must be one of: auto, always, never)", t.Tmux)
+	}
+	return nil
+}
+
+// validateFallback checks the fallback chain
+func (c *Config) validateFallback() error {
+	seen := make(map[string]bool)
+	for i, path := range c.Notification.Fallback {
+		if !slices.Contains(fallbackPaths, path) {
+			return fieldError(fmt.Errorf("unknown path: %s (must be one of: %s)", path, strings.Join(fallbackPaths, ", ")), "fallback", index(i))
+		}
+		if seen[path] {
+			return fieldError(fmt.Errorf("%s is listed twice", path), "fallback", index(i))
+		}
+		seen[path] = true
+	}
+	return fieldError(c.Notification.Terminal.validate(), "terminal")
+}
+
+// backendTypes lists the known backend types, in the order shown in errors
+var backendTypes = []string{BackendTypeDesktop, BackendTypeWebhook, BackendTypeCommand, BackendTypeFile, BackendTypeTerminal}
+
+// SoundConfig controls the sounds configured on levels
+type SoundConfig struct {
+	// Mute silences all sounds
+	Mute bool `yaml:"mute"`
+}
+
+// ScheduleConfig defines quiet hours during which less urgent notifications are deferred
+type ScheduleConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Timezone is an IANA name such as "Europe/Rome"; empty uses the local time zone
+	Timezone string `yaml:"timezone"`
+	// MinUrgency is the lowest urgency (low, normal, critical) still delivered during quiet hours
+	MinUrgency string `yaml:"min_urgency"`
+	// Deferred is DeferDigest to deliver deferred notifications as a digest when
+	// quiet hours end, or DeferHistory to only record them in the history
+	Deferred   string       `yaml:"deferred"`
+	QuietHours []QuietHours `yaml:"quiet_hours"`
+}
+
+// QuietHours is a quiet period starting on each of Days. Start and End are
+// "HH:MM"; an End not after Start ends on the following day
+type QuietHours struct {
+	Days  []string `yaml:"days"`
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+}
+
+// What happens to notifications deferred by quiet hours
+const (
+	DeferDigest  = "digest"
+	DeferHistory = "history"
+)
+
+// BatchConfig controls coalescing bursts of notifications into a digest
+type BatchConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Threshold is how many notifications within Window are delivered one by one
+	// before the rest are held back for a digest
+	Threshold int `yaml:"threshold"`
+	// Window is in seconds
+	Window int `yaml:"window"`
+}
+
+// HistoryConfig controls the notification history store
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Path defaults to history.jsonl in the platform-specific state directory
+	Path       string `yaml:"path"`
+	MaxEntries int    `yaml:"max_entries"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+}
+
+// Template contains message template configuration.
+// Templates use text/template syntax with .Title, .Message, .Level, .Timestamp,
+// .Workspace, .Client.Name and .Client.Version
 type Template struct {
+	// Default is the body template, used when Body is not set
 	Default string `yaml:"default"`
+	Title   string `yaml:"title"`
+	Body    string `yaml:"body"`
+}
+
+// BodyTemplate returns the template used for the notification body
+func (t Template) BodyTemplate() string {
+	if t.Body != "" {
+		return t.Body
+	}
+	return t.Default
+}
+
+// validate checks that the templates parse. Fields are only known when
+// rendering, where a template that fails falls back to the raw text
+func (t Template) validate() error {
+	templates := []struct{ name, text string }{
+		{"title", t.Title},
+		{"body", t.Body},
+		{"default", t.Default},
+	}
+	for _, tmpl := range templates {
+		if _, err := template.New(tmpl.name).Parse(tmpl.text); err != nil {
+			return fieldErrorf(tmpl.name, "invalid template: %w", err)
+		}
+	}
+	return nil
 }
 
 // Level contains configuration for a notification severity level
 type Level struct {
 	Urgency string `yaml:"urgency"`
 	Icon    string `yaml:"icon"`
+	// ExpireTimeout is in milliseconds: 0 uses the notification server default, -1 never expires
+	ExpireTimeout int    `yaml:"expire_timeout"`
+	Category      string `yaml:"category"`
+	// Sound is a freedesktop sound theme name (e.g. "message-new-instant") or a
+	// sound file played with the notification
+	Sound string `yaml:"sound"`
+	// Template overrides the global title/body templates for this level
+	Template Template `yaml:"template"`
+	// RateLimit overrides the default rate limit for this level
+	RateLimit RateLimit `yaml:"rate_limit"`
 }
 
+// validate checks the urgency, that icon and sound files exist and that the
+// templates and rate limit are usable
+func (l Level) validate() error {
+	switch l.Urgency {
+	case "", "low", "normal", "critical":
+	default:
+		return fieldErrorf("urgency", "unknown urgency: %s (must be one of: low, normal, critical)", l.Urgency)
+	}
+	if err := validatePath(l.Icon); err != nil {
+		return fieldError(err, "icon")
+	}
+	if err := validatePath(l.Sound); err != nil {
+		return fieldError(err, "sound")
+	}
+	if err := l.Template.validate(); err != nil {
+		return fieldError(err, "template")
+	}
+	return fieldError(l.RateLimit.validate(), "rate_limit")
+}
+
+// RateLimitConfig controls rate limiting and duplicate suppression
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DuplicateWindow suppresses identical title/message/level within this many seconds (0 disables)
+	DuplicateWindow int `yaml:"duplicate_window"`
+	// Default applies to levels without their own rate limit
+	Default RateLimit `yaml:"default"`
+}
+
+// RateLimit is a token bucket allowing Burst notifications at once, refilled at PerMinute.
+// A zero PerMinute disables the limit (or, on a level, falls back to the default)
+type RateLimit struct {
+	PerMinute int `yaml:"per_minute"`
+	Burst     int `yaml:"burst"`
+}
+
+// Supported notification backends
+const (
+	BackendAuto    = "auto"
+	BackendLibrary = "library"
+	BackendDBus    = "dbus"
+)
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
 		Notification: NotificationConfig{
-			DryRun:  false,
-			Verbose: false,
+			DryRun:   false,
+			Verbose:  false,
+			Backend:  BackendAuto,
+			Fallback: []string{BackendTypeDesktop, BackendTypeTerminal, BackendTypeFile},
 			Template: Template{
-				Default: "{{.Title}}: {{.Message}} [{{.Level}}]",
+				Default: "{{.Message}}",
+				Title:   "{{.Title}}",
 			},
 			Levels: map[string]Level{
 				"info": {
@@ -60,22 +471,419 @@ func DefaultConfig() *Config {
 					Icon:    "dialog-information",
 				},
 			},
+			DefaultLevel:  "info",
+			ActionTimeout: 120,
+			History: HistoryConfig{
+				Enabled:    true,
+				MaxEntries: 1000,
+				MaxAgeDays: 30,
+			},
+			RateLimit: RateLimitConfig{
+				Enabled:         false,
+				DuplicateWindow: 30,
+				Default: RateLimit{
+					PerMinute: 10,
+					Burst:     5,
+				},
+			},
+			Batch: BatchConfig{
+				Enabled:   false,
+				Threshold: 3,
+				Window:    60,
+			},
+			Schedule: ScheduleConfig{
+				Enabled:    false,
+				MinUrgency: "critical",
+				Deferred:   DeferDigest,
+			},
 		},
 	}
 }
 
-// Validate checks if the configuration is valid
+// Validate checks if the configuration is valid. Errors about a setting are
+// *FieldError values naming it
 func (c *Config) Validate() error {
-	// No validation needed for now
-	// Future: could validate level names, icon paths, etc.
+	if err := c.validateNotification(); err != nil {
+		return fieldError(err, "notification")
+	}
+
+	for i, token := range c.Server.Auth.Tokens {
+		if err := token.validate(); err != nil {
+			return fieldError(err, "server", "auth", "tokens", index(i))
+		}
+	}
 	return nil
 }
 
-// LoadConfig loads configuration from a file, or returns defaults if file doesn't exist
+// validateNotification checks the notification section
+func (c *Config) validateNotification() error {
+	switch c.Notification.Backend {
+	case BackendAuto, BackendLibrary, BackendDBus:
+	default:
+		return fieldErrorf("backend", "unknown backend: %s (must be one of: auto, library, dbus)", c.Notification.Backend)
+	}
+
+	if err := c.validateFallback(); err != nil {
+		return err
+	}
+
+	if err := c.Notification.Template.validate(); err != nil {
+		return fieldError(err, "template")
+	}
+
+	if len(c.Notification.Levels) == 0 {
+		return fieldErrorf("levels", "at least one level must be defined")
+	}
+	for _, name := range c.LevelNames() {
+		if name == "" {
+			return fieldErrorf("levels", "level names cannot be empty")
+		}
+		if err := c.Notification.Levels[name].validate(); err != nil {
+			return fieldError(err, "levels", name)
+		}
+	}
+	if _, ok := c.Notification.Levels[c.Notification.DefaultLevel]; !ok {
+		return fieldErrorf("default_level", "level %q is not defined in levels", c.Notification.DefaultLevel)
+	}
+
+	if c.Notification.ActionTimeout <= 0 {
+		return fieldErrorf("action_timeout", "must be positive, got %d", c.Notification.ActionTimeout)
+	}
+
+	if c.Notification.History.MaxEntries < 0 {
+		return fieldError(errors.New("cannot be negative"), "history", "max_entries")
+	}
+	if c.Notification.History.MaxAgeDays < 0 {
+		return fieldError(errors.New("cannot be negative"), "history", "max_age_days")
+	}
+
+	if c.Notification.RateLimit.DuplicateWindow < 0 {
+		return fieldError(errors.New("cannot be negative"), "rate_limit", "duplicate_window")
+	}
+	if err := c.Notification.RateLimit.Default.validate(); err != nil {
+		return fieldError(err, "rate_limit", "default")
+	}
+
+	if c.Notification.Batch.Threshold < 0 {
+		return fieldError(errors.New("cannot be negative"), "batch", "threshold")
+	}
+	if c.Notification.Batch.Enabled && c.Notification.Batch.Window <= 0 {
+		return fieldError(errors.New("must be positive"), "batch", "window")
+	}
+
+	if err := c.Notification.Schedule.validate(); err != nil {
+		return fieldError(err, "schedule")
+	}
+	// Notifications deferred to the history would otherwise be lost
+	if c.Notification.Schedule.Enabled && c.Notification.Schedule.Deferred == DeferHistory && !c.Notification.History.Enabled {
+		return fieldError(errors.New("requires history.enabled"), "schedule", "deferred")
+	}
+
+	if err := c.Notification.LogFile.validate(); err != nil {
+		return fieldError(err, "log_file")
+	}
+
+	return c.validateBackends()
+}
+
+// validate checks that the rate limit values are usable
+func (r RateLimit) validate() error {
+	if r.PerMinute < 0 {
+		return fieldErrorf("per_minute", "cannot be negative")
+	}
+	if r.Burst < 0 {
+		return fieldErrorf("burst", "cannot be negative")
+	}
+	return nil
+}
+
+// validate checks the settings of the backend's type
+func (b BackendConfig) validate() error {
+	switch b.Type {
+	case BackendTypeWebhook:
+		return fieldError(b.Webhook.validate(), "webhook")
+	case BackendTypeCommand:
+		return fieldError(b.Command.validate(), "command")
+	case BackendTypeFile:
+		return fieldError(b.File.validate(), "file")
+	case BackendTypeTerminal:
+		return fieldError(b.Terminal.validate(), "terminal")
+	}
+	return nil
+}
+
+// validateBackends checks that backends have unique names and known types,
+// and that routes only refer to defined backends and levels
+func (c *Config) validateBackends() error {
+	names := make(map[string]bool)
+	for i, backend := range c.Notification.Backends {
+		if backend.Name == "" {
+			return fieldError(errors.New("cannot be empty"), "backends", index(i), "name")
+		}
+		if names[backend.Name] {
+			return fieldError(fmt.Errorf("duplicate name %s", backend.Name), "backends", index(i), "name")
+		}
+		names[backend.Name] = true
+		if !slices.Contains(backendTypes, backend.Type) {
+			return fieldError(fmt.Errorf("unknown type: %s (must be one of: %s)",
+				backend.Type, strings.Join(backendTypes, ", ")), "backends", index(i), "type")
+		}
+		if err := backend.validate(); err != nil {
+			return fieldError(err, "backends", index(i))
+		}
+	}
+
+	for i, route := range c.Notification.Routes {
+		if len(route.Backends) == 0 {
+			return fieldError(errors.New("at least one backend must be listed"), "routes", index(i), "backends")
+		}
+		for j, name := range route.Backends {
+			if !names[name] {
+				return fieldError(fmt.Errorf("backend %s is not defined in backends", name), "routes", index(i), "backends", index(j))
+			}
+		}
+		for j, level := range route.Levels {
+			if _, ok := c.Notification.Levels[level]; !ok {
+				return fieldError(fmt.Errorf("level %s is not defined in levels", level), "routes", index(i), "levels", index(j))
+			}
+		}
+		for j, pattern := range route.Workspaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fieldError(fmt.Errorf("invalid workspace pattern %q: %w", pattern, err), "routes", index(i), "workspaces", index(j))
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks that both the name and the token are set
+func (t Token) validate() error {
+	if t.Name == "" {
+		return fieldErrorf("name", "cannot be empty")
+	}
+	if t.Token == "" {
+		return fieldErrorf("token", "cannot be empty")
+	}
+	return nil
+}
+
+// LoadTokens returns the configured tokens followed by those in the token file
+func (a AuthConfig) LoadTokens() ([]Token, error) {
+	tokens := append([]Token(nil), a.Tokens...)
+	if a.TokenFile == "" {
+		return tokens, nil
+	}
+
+	data, err := os.ReadFile(a.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("token file %s:%d: expected \"name token\"", a.TokenFile, i+1)
+		}
+		tokens = append(tokens, Token{Name: fields[0], Token: fields[1]})
+	}
+	return tokens, nil
+}
+
+// validate checks the schedule settings and that every quiet period parses
+func (s ScheduleConfig) validate() error {
+	if _, err := s.Location(); err != nil {
+		return fieldError(err, "timezone")
+	}
+
+	switch s.MinUrgency {
+	case "low", "normal", "critical":
+	default:
+		return fieldErrorf("min_urgency", "unknown urgency: %s (must be one of: low, normal, critical)", s.MinUrgency)
+	}
+
+	switch s.Deferred {
+	case DeferDigest, DeferHistory:
+	default:
+		return fieldErrorf("deferred", "unknown deferred: %s (must be one of: digest, history)", s.Deferred)
+	}
+
+	for i, q := range s.QuietHours {
+		if _, _, _, err := q.Parse(); err != nil {
+			return fieldError(err, "quiet_hours", index(i))
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone quiet hours are evaluated in
+func (s ScheduleConfig) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+	return loc, nil
+}
+
+// QuietUntil reports whether t falls in quiet hours and, if so, when they end.
+// Back-to-back quiet periods, such as a weeknight followed by a quiet weekend,
+// count as one
+func (s ScheduleConfig) QuietUntil(t time.Time) (time.Time, bool) {
+	if !s.Enabled {
+		return time.Time{}, false
+	}
+	loc, err := s.Location()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	until, quiet := t.In(loc), false
+	// Periods start on a weekday, so a chain longer than a week repeats itself
+	for i := 0; i < 8; i++ {
+		end, ok := s.quietPeriodEnd(until)
+		if !ok {
+			break
+		}
+		until, quiet = end, true
+	}
+	return until, quiet
+}
+
+// quietPeriodEnd returns the latest end of the quiet periods containing t
+func (s ScheduleConfig) quietPeriodEnd(t time.Time) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for _, q := range s.QuietHours {
+		days, start, end, err := q.Parse()
+		if err != nil {
+			continue
+		}
+		// A period containing t started today or, if it runs past midnight, yesterday
+		for _, offset := range []int{0, -1} {
+			day := t.AddDate(0, 0, offset)
+			if !slices.Contains(days, day.Weekday()) {
+				continue
+			}
+			from := clockOn(day, start)
+			to := clockOn(day, end)
+			if !to.After(from) {
+				to = clockOn(day.AddDate(0, 0, 1), end)
+			}
+			if !t.Before(from) && t.Before(to) && to.After(latest) {
+				latest, found = to, true
+			}
+		}
+	}
+	return latest, found
+}
+
+// clockOn returns the time of day offset from midnight on day's date
+func clockOn(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// weekdays maps day names, full or abbreviated, to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Parse returns the days the quiet period starts on and its start and end as
+// offsets from midnight. End is "24:00" for a period lasting until midnight
+func (q QuietHours) Parse() (days []time.Weekday, start, end time.Duration, err error) {
+	if len(q.Days) == 0 {
+		return nil, 0, 0, fmt.Errorf("at least one day must be listed")
+	}
+	for _, name := range q.Days {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, 0, 0, fmt.Errorf("unknown day: %s", name)
+		}
+		days = append(days, day)
+	}
+
+	if start, err = parseClock(q.Start); err != nil {
+		return nil, 0, 0, fmt.Errorf("start: %w", err)
+	}
+	if start == 24*time.Hour {
+		return nil, 0, 0, fmt.Errorf("start: must be before 24:00")
+	}
+	if end, err = parseClock(q.End); err != nil {
+		return nil, 0, 0, fmt.Errorf("end: %w", err)
+	}
+	return days, start, end, nil
+}
+
+// parseClock parses an "HH:MM" time of day, allowing "24:00" for midnight at the end of the day
+func parseClock(clock string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(clock, ":")
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", clock)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// RateLimitFor returns the rate limit for a level, falling back to the default
+func (c *Config) RateLimitFor(level string) RateLimit {
+	if limit := c.Notification.Levels[level].RateLimit; limit.PerMinute > 0 {
+		return limit
+	}
+	return c.Notification.RateLimit.Default
+}
+
+// LevelNames returns the configured level names in sorted order
+func (c *Config) LevelNames() []string {
+	names := make([]string, 0, len(c.Notification.Levels))
+	for name := range c.Notification.Levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TemplateFor returns the templates for a level, with the level's own
+// title/body templates taking precedence over the global ones
+func (c *Config) TemplateFor(level string) Template {
+	tmpl := c.Notification.Template
+	levelTemplate := c.Notification.Levels[level].Template
+
+	if levelTemplate.Title != "" {
+		tmpl.Title = levelTemplate.Title
+	}
+	if body := levelTemplate.BodyTemplate(); body != "" {
+		tmpl.Body = body
+	}
+	return tmpl
+}
+
+// LoadConfig loads configuration from a file, or uses the defaults if the file
+// doesn't exist, then applies the MCP_POKE_* environment variables
 func LoadConfig(path string) (*Config, error) {
-	// If file doesn't exist, return default config
+	return LoadLayers([]Layer{{Name: LayerUser, Path: path}})
+}
+
+// loadFile merges the configuration file of layer into c, if it exists, and
+// returns its document for locating errors
+func (c *Config) loadFile(layer Layer) (*yaml.Node, error) {
+	path := layer.Path
+
+	// If file doesn't exist, keep the current settings
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return DefaultConfig(), nil
+		return nil, nil
 	}
 
 	// Read the file
@@ -84,42 +892,122 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Start with default config
-	cfg := DefaultConfig()
+	// Parse the document first, keeping the positions for error messages
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		// An empty file keeps the current settings
+		return nil, nil
+	}
 
-	// Unmarshal YAML, merging with defaults
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Reject unknown keys, which the decoder would ignore
+	errs := checkKeys(document.Content[0], reflect.TypeOf(*c), nil)
+	if layer.Name == LayerProject {
+		errs = append(errs, checkProjectKeys(document.Content[0])...)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", path, errors.Join(errs...))
 	}
 
-	// Validate the config
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	// Decode YAML, merging with the current settings
+	if err := merge(document.Content[0], reflect.ValueOf(c).Elem()); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
+	c.recordSources(document.Content[0], reflect.TypeOf(*c), nil, path)
+
+	return &document, nil
+}
 
-	return cfg, nil
+// ConfigPath is a place the configuration file is looked up in
+type ConfigPath struct {
+	// Source describes where the location comes from, e.g. $XDG_CONFIG_HOME
+	Source string
+	// Path is empty when Source is an unset environment variable
+	Path string
+}
+
+// ConfigPaths returns the platform-specific config file locations in order of
+// precedence. The first one with a Path is used, whether the file exists or not
+func ConfigPaths() []ConfigPath {
+	if runtime.GOOS == "windows" {
+		// Windows: %APPDATA%\mcp-desktop-notification\config.yaml
+		return []ConfigPath{
+			configPathIn("%APPDATA%", os.Getenv("APPDATA")),
+			configPathIn(`%USERPROFILE%\AppData\Roaming`, filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Roaming")),
+		}
+	}
+
+	// Linux/macOS: XDG Base Directory specification
+	return []ConfigPath{
+		configPathIn("$XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME")),
+		configPathIn("$HOME/.config", filepath.Join(os.Getenv("HOME"), ".config")),
+	}
+}
+
+// configPathIn returns the config file location in dir, unless dir is empty
+func configPathIn(source, dir string) ConfigPath {
+	if dir == "" {
+		return ConfigPath{Source: source}
+	}
+	return ConfigPath{Source: source, Path: filepath.Join(dir, "mcp-desktop-notification", "config.yaml")}
 }
 
 // GetConfigPath returns the platform-specific config file path
 func GetConfigPath() string {
+	for _, p := range ConfigPaths() {
+		if p.Path != "" {
+			return p.Path
+		}
+	}
+	return ""
+}
+
+// GetStateDir returns the platform-specific directory for state such as the notification history
+func GetStateDir() string {
 	if runtime.GOOS == "windows" {
-		// Windows: %APPDATA%\mcp-desktop-notification\config.yaml
-		appData := os.Getenv("APPDATA")
-		if appData == "" {
-			appData = filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Roaming")
+		// Windows: %LOCALAPPDATA%\mcp-desktop-notification
+		localAppData := os.Getenv("LOCALAPPDATA")
+		if localAppData == "" {
+			localAppData = filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Local")
 		}
-		return filepath.Join(appData, "mcp-desktop-notification", "config.yaml")
+		return filepath.Join(localAppData, "mcp-desktop-notification")
 	}
 
 	// Linux/macOS: XDG Base Directory specification
-	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
-	if xdgConfigHome == "" {
-		xdgConfigHome = filepath.Join(os.Getenv("HOME"), ".config")
+	xdgStateHome := os.Getenv("XDG_STATE_HOME")
+	if xdgStateHome == "" {
+		xdgStateHome = filepath.Join(os.Getenv("HOME"), ".local", "state")
+	}
+	return filepath.Join(xdgStateHome, "mcp-desktop-notification")
+}
+
+// GetSocketPath returns the per-user socket the local daemon listens on
+func GetSocketPath() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		// Without a runtime directory use a private per-user directory in the
+		// shared temp directory, which the daemon creates with mode 0700
+		return filepath.Join(os.TempDir(), fmt.Sprintf("mcp-poke-%d", os.Getuid()), "mcp-poke.sock")
+	}
+	return filepath.Join(runtimeDir, "mcp-poke.sock")
+}
+
+// DeferredPath returns the file keeping the notifications deferred during quiet hours
+func DeferredPath() string {
+	return filepath.Join(GetStateDir(), "deferred.jsonl")
+}
+
+// HistoryPath returns the configured history file, or the default one in the state directory
+func (c *Config) HistoryPath() string {
+	if c.Notification.History.Path != "" {
+		return c.Notification.History.Path
 	}
-	return filepath.Join(xdgConfigHome, "mcp-desktop-notification", "config.yaml")
+	return filepath.Join(GetStateDir(), "history.jsonl")
 }
 
-// LoadDefaultConfig loads config from the default platform-specific path
+// LoadDefaultConfig loads the system, user and project configuration files
 func LoadDefaultConfig() (*Config, error) {
-	return LoadConfig(GetConfigPath())
+	return LoadLayers(Layers(GetConfigPath()))
 }
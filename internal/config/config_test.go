@@ -1,9 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -17,6 +20,11 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("Expected Verbose to be false by default")
 	}
 
+	// Existing setups keep receiving every notification until they opt in
+	if cfg.Notification.RateLimit.Enabled {
+		t.Error("Expected rate limiting to be disabled by default")
+	}
+
 	if cfg.Notification.Template.Default == "" {
 		t.Error("Expected default template to be set")
 	}
@@ -186,3 +194,568 @@ func TestGetConfigPath_Windows(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, path)
 	}
 }
+
+func TestValidateConfig_Backend(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Notification.Backend != BackendAuto {
+		t.Errorf("Expected default backend %q, got %q", BackendAuto, cfg.Notification.Backend)
+	}
+
+	for _, backend := range []string{BackendAuto, BackendLibrary, BackendDBus} {
+		cfg.Notification.Backend = backend
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected backend %q to be valid, got error: %v", backend, err)
+		}
+	}
+
+	cfg.Notification.Backend = "carrier-pigeon"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
+
+func TestValidateConfig_Templates(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notification.Template.Title = "{{.Workspace}}: {{.Title}}"
+	cfg.Notification.Template.Body = "{{.Message}} [{{.Level}}]"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid templates, got error: %v", err)
+	}
+
+	cfg.Notification.Template.Body = "{{.Message"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unparsable body template")
+	}
+}
+
+func TestLoadConfig_InvalidTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+notification:
+  template:
+    title: "{{if .Title}}"
+`
+
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected error for invalid title template")
+	}
+}
+
+func TestLoadConfig_CustomLevels(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yamlContent := `
+notification:
+  default_level: progress
+  levels:
+    progress:
+      urgency: low
+      icon: "view-refresh"
+    approval:
+      urgency: critical
+      icon: "dialog-question"
+      template:
+        title: "Approval needed: {{.Title}}"
+        body: "{{.Message}} ({{.Workspace}})"
+`
+
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Notification.DefaultLevel != "progress" {
+		t.Errorf("Expected default level 'progress', got %q", cfg.Notification.DefaultLevel)
+	}
+
+	// Custom levels are added alongside the built-in ones
+	expected := []string{"approval", "error", "info", "progress", "success", "warning"}
+	names := cfg.LevelNames()
+	if len(names) != len(expected) {
+		t.Fatalf("Expected levels %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected levels %v, got %v", expected, names)
+			break
+		}
+	}
+
+	if cfg.Notification.Levels["approval"].Template.Title != "Approval needed: {{.Title}}" {
+		t.Errorf("Expected per-level title template, got %q", cfg.Notification.Levels["approval"].Template.Title)
+	}
+}
+
+func TestTemplateFor(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notification.Template.Title = "global title"
+	cfg.Notification.Template.Body = "global body"
+	cfg.Notification.Levels["error"] = Level{
+		Urgency:  "critical",
+		Template: Template{Title: "error title"},
+	}
+	cfg.Notification.Levels["success"] = Level{
+		Urgency:  "low",
+		Template: Template{Default: "success body"},
+	}
+
+	tests := []struct {
+		level         string
+		expectedTitle string
+		expectedBody  string
+	}{
+		{"info", "global title", "global body"},
+		{"error", "error title", "global body"},
+		{"success", "global title", "success body"},
+		{"unknown", "global title", "global body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			tmpl := cfg.TemplateFor(tt.level)
+			if tmpl.Title != tt.expectedTitle {
+				t.Errorf("Expected title %q, got %q", tt.expectedTitle, tmpl.Title)
+			}
+			if tmpl.BodyTemplate() != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, tmpl.BodyTemplate())
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Levels(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notification.DefaultLevel = "missing"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for undefined default level")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Notification.Levels = map[string]Level{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error when no levels are defined")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Notification.Levels["broken"] = Level{Template: Template{Body: "{{.Message"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for invalid per-level template")
+	}
+}
+
+func TestGetStateDir_XDG(t *testing.T) {
+	if filepath.Separator == '\\' {
+		t.Skip("Skipping XDG test on Windows")
+	}
+
+	t.Setenv("XDG_STATE_HOME", "/custom/state")
+	if dir := GetStateDir(); dir != "/custom/state/mcp-desktop-notification" {
+		t.Errorf("Expected /custom/state/mcp-desktop-notification, got %s", dir)
+	}
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/user")
+	if dir := GetStateDir(); dir != "/home/user/.local/state/mcp-desktop-notification" {
+		t.Errorf("Expected /home/user/.local/state/mcp-desktop-notification, got %s", dir)
+	}
+}
+
+func TestHistoryPath(t *testing.T) {
+	cfg := DefaultConfig()
+	if !cfg.Notification.History.Enabled {
+		t.Error("Expected history to be enabled by default")
+	}
+
+	expected := filepath.Join(GetStateDir(), "history.jsonl")
+	if path := cfg.HistoryPath(); path != expected {
+		t.Errorf("Expected default history path %s, got %s", expected, path)
+	}
+
+	cfg.Notification.History.Path = "/tmp/custom-history.jsonl"
+	if path := cfg.HistoryPath(); path != "/tmp/custom-history.jsonl" {
+		t.Errorf("Expected configured history path, got %s", path)
+	}
+
+	cfg.Notification.History.MaxEntries = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for negative history limit")
+	}
+}
+
+func TestRateLimitFor(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notification.RateLimit.Default = RateLimit{PerMinute: 10, Burst: 5}
+	errorLevel := cfg.Notification.Levels["error"]
+	errorLevel.RateLimit = RateLimit{PerMinute: 2, Burst: 1}
+	cfg.Notification.Levels["error"] = errorLevel
+
+	if limit := cfg.RateLimitFor("error"); limit.PerMinute != 2 || limit.Burst != 1 {
+		t.Errorf("Expected per-level rate limit, got %+v", limit)
+	}
+	if limit := cfg.RateLimitFor("info"); limit.PerMinute != 10 || limit.Burst != 5 {
+		t.Errorf("Expected default rate limit, got %+v", limit)
+	}
+
+	cfg.Notification.RateLimit.Default.Burst = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for negative burst")
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	schedule := ScheduleConfig{
+		Enabled:  true,
+		Timezone: "UTC",
+		QuietHours: []QuietHours{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "22:00", End: "07:00"},
+			{Days: []string{"Saturday", "sun"}, Start: "00:00", End: "24:00"},
+		},
+	}
+
+	// 2025-01-06 is a Monday
+	tests := []struct {
+		name  string
+		at    time.Time
+		quiet bool
+		until time.Time
+	}{
+		{"weekday afternoon", time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"weeknight", time.Date(2025, 1, 6, 23, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)},
+		{"after midnight", time.Date(2025, 1, 7, 3, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)},
+		{"end is not quiet", time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"friday night runs into weekend", time.Date(2025, 1, 10, 23, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)},
+		{"sunday night before monday", time.Date(2025, 1, 12, 23, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := schedule.QuietUntil(tt.at)
+			if quiet != tt.quiet {
+				t.Fatalf("Expected quiet=%v, got %v", tt.quiet, quiet)
+			}
+			if quiet && !until.Equal(tt.until) {
+				t.Errorf("Expected quiet hours to end at %v, got %v", tt.until, until)
+			}
+		})
+	}
+
+	schedule.Enabled = false
+	if _, quiet := schedule.QuietUntil(time.Date(2025, 1, 6, 23, 0, 0, 0, time.UTC)); quiet {
+		t.Error("Expected no quiet hours when the schedule is disabled")
+	}
+}
+
+func TestQuietUntil_Timezone(t *testing.T) {
+	schedule := ScheduleConfig{
+		Enabled:    true,
+		Timezone:   "Europe/Rome",
+		QuietHours: []QuietHours{{Days: []string{"mon"}, Start: "22:00", End: "23:00"}},
+	}
+	if _, err := schedule.Location(); err != nil {
+		t.Skipf("Skipping: time zone database not available: %v", err)
+	}
+
+	// 21:30 UTC is 22:30 in Rome in winter
+	if _, quiet := schedule.QuietUntil(time.Date(2025, 1, 6, 21, 30, 0, 0, time.UTC)); !quiet {
+		t.Error("Expected quiet hours to be evaluated in the configured time zone")
+	}
+}
+
+func TestValidateConfig_Schedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*ScheduleConfig)
+	}{
+		{"unknown timezone", func(s *ScheduleConfig) { s.Timezone = "Mars/Olympus" }},
+		{"unknown urgency", func(s *ScheduleConfig) { s.MinUrgency = "urgent" }},
+		{"unknown deferred", func(s *ScheduleConfig) { s.Deferred = "drop" }},
+		{"unknown day", func(s *ScheduleConfig) {
+			s.QuietHours = []QuietHours{{Days: []string{"funday"}, Start: "22:00", End: "07:00"}}
+		}},
+		{"no days", func(s *ScheduleConfig) {
+			s.QuietHours = []QuietHours{{Start: "22:00", End: "07:00"}}
+		}},
+		{"bad time", func(s *ScheduleConfig) {
+			s.QuietHours = []QuietHours{{Days: []string{"mon"}, Start: "25:00", End: "07:00"}}
+		}},
+		{"start at end of day", func(s *ScheduleConfig) {
+			s.QuietHours = []QuietHours{{Days: []string{"mon"}, Start: "24:00", End: "07:00"}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg.Notification.Schedule)
+			if err := cfg.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestValidateConfig_DeferredToHistory(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notification.Schedule.Enabled = true
+	cfg.Notification.Schedule.Deferred = DeferHistory
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected deferring to the history to be valid, got: %v", err)
+	}
+
+	cfg.Notification.History.Enabled = false
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "notification.schedule.deferred: requires history.enabled") {
+		t.Errorf("Expected deferring to a disabled history to be rejected, got: %v", err)
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens")
+	content := "# agents allowed to notify\ncontainer abc123\n\n  devbox   def456  \n"
+	if err := os.WriteFile(tokenFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	auth := AuthConfig{
+		Tokens:    []Token{{Name: "laptop", Token: "xyz789"}},
+		TokenFile: tokenFile,
+	}
+	tokens, err := auth.LoadTokens()
+	if err != nil {
+		t.Fatalf("LoadTokens failed: %v", err)
+	}
+
+	expected := []Token{{"laptop", "xyz789"}, {"container", "abc123"}, {"devbox", "def456"}}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %+v", len(expected), tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Token %d: expected %+v, got %+v", i, expected[i], tokens[i])
+		}
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("only-a-name\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	if _, err := auth.LoadTokens(); err == nil {
+		t.Error("Expected error for a malformed token file line")
+	}
+}
+
+func TestValidateConfig_Tokens(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Server.Auth.Tokens = []Token{{Name: "devbox"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for a token without a value")
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	route := Route{Levels: []string{"error", "warning"}, Workspaces: []string{"projects/*"}}
+
+	tests := []struct {
+		level, workspace string
+		expected         bool
+	}{
+		{"error", "projects/foo", true},
+		{"warning", "projects/bar", true},
+		{"info", "projects/foo", false},
+		{"error", "work/foo", false},
+	}
+	for _, tt := range tests {
+		if got := route.Matches(tt.level, tt.workspace); got != tt.expected {
+			t.Errorf("Matches(%q, %q) = %v, expected %v", tt.level, tt.workspace, got, tt.expected)
+		}
+	}
+
+	if !(Route{}).Matches("info", "anything") {
+		t.Error("Expected a route without conditions to match everything")
+	}
+}
+
+func TestValidateConfig_Backends(t *testing.T) {
+	desktop := BackendConfig{Name: "desktop", Type: BackendTypeDesktop}
+	tests := []struct {
+		name     string
+		backends []BackendConfig
+		routes   []Route
+	}{
+		{"missing name", []BackendConfig{{Type: BackendTypeDesktop}}, nil},
+		{"duplicate name", []BackendConfig{desktop, desktop}, nil},
+		{"unknown type", []BackendConfig{{Name: "pager", Type: "pager"}}, nil},
+		{"route without backends", []BackendConfig{desktop}, []Route{{Levels: []string{"error"}}}},
+		{"route to undefined backend", []BackendConfig{desktop}, []Route{{Backends: []string{"file"}}}},
+		{"route with undefined level", []BackendConfig{desktop}, []Route{{Levels: []string{"fatal"}, Backends: []string{"desktop"}}}},
+		{"invalid workspace pattern", []BackendConfig{desktop}, []Route{{Workspaces: []string{"["}, Backends: []string{"desktop"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Notification.Backends = tt.backends
+			cfg.Notification.Routes = tt.routes
+			if err := cfg.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Notification.Backends = []BackendConfig{desktop}
+	cfg.Notification.Routes = []Route{{Levels: []string{"error"}, Workspaces: []string{"projects/*"}, Backends: []string{"desktop"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid backends and routes, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Webhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook WebhookConfig
+		valid   bool
+	}{
+		{"valid", WebhookConfig{URL: "https://ntfy.sh/my-topic", Format: WebhookFormatNtfy, Retries: 3}, true},
+		{"default format", WebhookConfig{URL: "http://localhost:8080/hook"}, true},
+		{"missing url", WebhookConfig{Format: WebhookFormatSlack}, false},
+		{"not http", WebhookConfig{URL: "ftp://example.com"}, false},
+		{"unknown format", WebhookConfig{URL: "https://example.com", Format: "teams"}, false},
+		{"negative retries", WebhookConfig{URL: "https://example.com", Retries: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Notification.Backends = []BackendConfig{{Name: "phone", Type: BackendTypeWebhook, Webhook: tt.webhook}}
+			err := cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid webhook, got: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Command(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		valid   bool
+	}{
+		{"valid", CommandConfig{Args: []string{"notify-send", "{{.Title}}", "{{.Message}}"}, Timeout: 5}, true},
+		{"no program", CommandConfig{}, false},
+		{"invalid template", CommandConfig{Args: []string{"echo", "{{.Title"}}, false},
+		{"negative timeout", CommandConfig{Args: []string{"true"}, Timeout: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Notification.Backends = []BackendConfig{{Name: "script", Type: BackendTypeCommand, Command: tt.command}}
+			err := cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid command, got: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestLogFilePath(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Notification.LogFile.Enabled {
+		t.Error("Expected the notification log file to be disabled by default")
+	}
+
+	expected := filepath.Join(GetStateDir(), "notifications.jsonl")
+	if path := cfg.Notification.LogFile.LogFilePath(); path != expected {
+		t.Errorf("Expected default log file %s, got %s", expected, path)
+	}
+
+	cfg.Notification.LogFile.MaxBackups = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for negative log file backups")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Notification.Backends = []BackendConfig{{Name: "audit", Type: BackendTypeFile, File: FileConfig{MaxSizeMB: -1}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for negative file backend size")
+	}
+}
+
+func TestValidateConfig_Fallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback []string
+		terminal TerminalConfig
+		valid    bool
+	}{
+		{"default", DefaultConfig().Notification.Fallback, TerminalConfig{}, true},
+		{"desktop only", []string{"desktop"}, TerminalConfig{}, true},
+		{"empty", nil, TerminalConfig{}, true},
+		{"terminal first", []string{"terminal", "desktop"}, TerminalConfig{Sequence: TerminalSequenceOSC777}, true},
+		{"unknown path", []string{"desktop", "pager"}, TerminalConfig{}, false},
+		{"duplicate path", []string{"desktop", "desktop"}, TerminalConfig{}, false},
+		{"unknown sequence", []string{"terminal"}, TerminalConfig{Sequence: "osc1337"}, false},
+		{"kitty in tmux", []string{"terminal"}, TerminalConfig{Sequence: TerminalSequenceOSC99, Tmux: TmuxAlways}, true},
+		{"unknown tmux mode", []string{"terminal"}, TerminalConfig{Tmux: "sometimes"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Notification.Fallback = tt.fallback
+			cfg.Notification.Terminal = tt.terminal
+			err := cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid fallback, got: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestValidateConfig_TerminalBackend(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notification.Backends = []BackendConfig{{
+		Name: "laptop", Type: BackendTypeTerminal,
+		Terminal: TerminalConfig{Sequence: TerminalSequenceOSC777, TTY: "/dev/pts/3"},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid terminal backend, got: %v", err)
+	}
+
+	cfg.Notification.Backends[0].Terminal.Sequence = "osc1337"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown terminal sequence")
+	}
+}
+
+func TestGetSocketPath_NoRuntimeDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", "/tmp")
+
+	// The socket is kept in a private directory rather than directly in /tmp
+	expected := filepath.Join(os.TempDir(), fmt.Sprintf("mcp-poke-%d", os.Getuid()), "mcp-poke.sock")
+	if path := GetSocketPath(); path != expected {
+		t.Errorf("Expected %s, got %s", expected, path)
+	}
+}
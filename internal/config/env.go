@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables overriding settings. The rest of
+// the name is the path to the setting in upper case, joined by underscores and
+// without the notification section, e.g. MCP_POKE_DRY_RUN for
+// notification.dry_run, MCP_POKE_LEVELS_ERROR_ICON for
+// notification.levels.error.icon and MCP_POKE_SERVER_AUTH_TOKEN_FILE
+const EnvPrefix = "MCP_POKE_"
+
+// envSetting is a setting that can be set from the environment
+type envSetting struct {
+	keys []string
+	typ  reflect.Type
+}
+
+// envMap is a map setting whose entries are set from the environment as
+// <prefix><KEY>_<setting of the entry>, e.g. the levels
+type envMap struct {
+	keys     []string
+	prefix   string
+	settings map[string]envSetting
+}
+
+// envSettings lists the settings of type t under keys by environment variable
+// name suffix, and the maps among them
+func envSettings(t reflect.Type, keys []string) (map[string]envSetting, []envMap) {
+	settings := make(map[string]envSetting)
+	var maps []envMap
+	for name, field := range yamlFields(t) {
+		fieldKeys := append(append([]string(nil), keys...), name)
+		switch {
+		case field.Kind() == reflect.Struct:
+			nested, nestedMaps := envSettings(field, fieldKeys)
+			for suffix, setting := range nested {
+				settings[suffix] = setting
+			}
+			maps = append(maps, nestedMaps...)
+		case field.Kind() == reflect.Map && field.Elem().Kind() == reflect.Struct:
+			entry, _ := envSettings(field.Elem(), nil)
+			maps = append(maps, envMap{keys: fieldKeys, prefix: envName(fieldKeys) + "_", settings: entry})
+		default:
+			settings[envName(fieldKeys)] = envSetting{keys: fieldKeys, typ: field}
+		}
+	}
+	return settings, maps
+}
+
+// envName returns the variable name suffix for the setting at keys
+func envName(keys []string) string {
+	if len(keys) > 0 && keys[0] == "notification" {
+		keys = keys[1:]
+	}
+	return strings.ToUpper(strings.Join(keys, "_"))
+}
+
+// applyEnv sets the settings named by the MCP_POKE_* variables of environ,
+// given as "NAME=value" pairs, recording them as their source
+func (c *Config) applyEnv(environ []string) error {
+	settings, maps := envSettings(reflect.TypeOf(*c), nil)
+
+	// Apply in a stable order so that errors are reproducible
+	for _, variable := range slices.Sorted(slices.Values(environ)) {
+		name, value, _ := strings.Cut(variable, "=")
+		suffix, ok := strings.CutPrefix(name, EnvPrefix)
+		if !ok || suffix == "" {
+			continue
+		}
+
+		keys, typ, err := c.resolveEnv(suffix, settings, maps)
+		if err != nil {
+			return fmt.Errorf("$%s: %w", name, err)
+		}
+		parsed, err := parseEnvValue(value, typ)
+		if err != nil {
+			return fmt.Errorf("$%s: invalid value %q: %w", name, value, err)
+		}
+		setValue(reflect.ValueOf(c).Elem(), keys, parsed)
+		c.SetSource(keys, "$"+name)
+	}
+	return nil
+}
+
+// resolveEnv returns the setting a variable name suffix refers to
+func (c *Config) resolveEnv(suffix string, settings map[string]envSetting, maps []envMap) ([]string, reflect.Type, error) {
+	if setting, ok := settings[suffix]; ok {
+		return setting.keys, setting.typ, nil
+	}
+
+	for _, m := range maps {
+		rest, ok := strings.CutPrefix(suffix, m.prefix)
+		if !ok {
+			continue
+		}
+		// The longest setting name wins, e.g. TEMPLATE_TITLE over TITLE
+		best := ""
+		for name := range m.settings {
+			if len(name) > len(best) && strings.HasSuffix(rest, "_"+name) {
+				best = name
+			}
+		}
+		if best == "" {
+			continue
+		}
+		key := c.mapKey(m.keys, strings.TrimSuffix(rest, "_"+best))
+		setting := m.settings[best]
+		return append(append(append([]string(nil), m.keys...), key), setting.keys...), setting.typ, nil
+	}
+
+	if closest := closestKey(suffix, settings); closest != "" {
+		return nil, nil, fmt.Errorf("unknown setting (did you mean $%s%s?)", EnvPrefix, closest)
+	}
+	return nil, nil, fmt.Errorf("unknown setting")
+}
+
+// mapKey returns the entry of the map at keys matching name regardless of
+// case, or name in lower case for a new entry
+func (c *Config) mapKey(keys []string, name string) string {
+	m := valueAt(reflect.ValueOf(c).Elem(), keys)
+	for _, key := range m.MapKeys() {
+		if strings.EqualFold(key.String(), name) {
+			return key.String()
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// parseEnvValue parses value for a setting of type t. Strings are taken as
+// they are, booleans as strconv.ParseBool does, lists as comma-separated
+// values or YAML flow sequences, and the rest as YAML
+func parseEnvValue(value string, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(value).Convert(t), nil
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		return reflect.ValueOf(b).Convert(t), err
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		list := reflect.MakeSlice(t, 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(t.Elem()))
+			}
+		}
+		return list, nil
+	}
+
+	parsed := reflect.New(t)
+	if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return parsed.Elem(), nil
+}
+
+// valueAt returns the setting at keys in v, a struct. Map entries are copies
+func valueAt(v reflect.Value, keys []string) reflect.Value {
+	for _, key := range keys {
+		switch v.Kind() {
+		case reflect.Struct:
+			v = fieldByKey(v, key)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(key))
+		}
+	}
+	return v
+}
+
+// setValue sets the setting at keys in v, an addressable struct, creating
+// map entries as needed
+func setValue(v reflect.Value, keys []string, value reflect.Value) {
+	if len(keys) == 0 {
+		v.Set(value)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		setValue(fieldByKey(v, keys[0]), keys[1:], value)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(keys[0])
+		// Map entries are not addressable, so update a copy
+		entry := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			entry.Set(existing)
+		}
+		setValue(entry, keys[1:], value)
+		v.SetMapIndex(key, entry)
+	}
+}
+
+// fieldByKey returns the field of struct v with the YAML key, looking into
+// inlined structs
+func fieldByKey(v reflect.Value, key string) reflect.Value {
+	var found reflect.Value
+	eachField(v, func(name string, field reflect.Value) {
+		if name == key && !found.IsValid() {
+			found = field
+		}
+	})
+	return found
+}
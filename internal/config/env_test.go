@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	cfg := DefaultConfig()
+	err := cfg.applyEnv([]string{
+		"MCP_POKE_DRY_RUN=1",
+		"MCP_POKE_ACTION_TIMEOUT=30",
+		"MCP_POKE_FALLBACK=terminal, file",
+		"MCP_POKE_TEMPLATE_TITLE={{.Level}}: {{.Title}}",
+		"MCP_POKE_LEVELS_ERROR_ICON=/usr/share/icons/error.png",
+		"MCP_POKE_LEVELS_DEPLOY_URGENCY=low",
+		"MCP_POKE_LEVELS_WARNING_RATE_LIMIT_PER_MINUTE=2",
+		"MCP_POKE_LOG_FILE_MAX_SIZE_MB=5",
+		"MCP_POKE_SERVER_AUTH_TOKEN_FILE=/etc/mcp-poke/tokens",
+		"MCP_POKE_SCHEDULE_QUIET_HOURS=[{days: [sat, sun], start: \"00:00\", end: \"24:00\"}]",
+		"PATH=/usr/bin",
+	})
+	if err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+
+	if !cfg.Notification.DryRun {
+		t.Error("Expected dry_run from the environment")
+	}
+	if cfg.Notification.ActionTimeout != 30 {
+		t.Errorf("Expected action_timeout 30, got %d", cfg.Notification.ActionTimeout)
+	}
+	if !slices.Equal(cfg.Notification.Fallback, []string{"terminal", "file"}) {
+		t.Errorf("Expected a comma-separated fallback, got %v", cfg.Notification.Fallback)
+	}
+	if cfg.Notification.Template.Title != "{{.Level}}: {{.Title}}" {
+		t.Errorf("Expected the template as is, got %q", cfg.Notification.Template.Title)
+	}
+
+	// Setting a field keeps the rest of the level
+	errorLevel := cfg.Notification.Levels["error"]
+	if errorLevel.Icon != "/usr/share/icons/error.png" || errorLevel.Urgency != "critical" {
+		t.Errorf("Expected the error icon to change and the urgency to stay, got %+v", errorLevel)
+	}
+	if cfg.Notification.Levels["deploy"].Urgency != "low" {
+		t.Errorf("Expected a new deploy level, got %+v", cfg.Notification.Levels)
+	}
+	if cfg.Notification.Levels["warning"].RateLimit.PerMinute != 2 {
+		t.Errorf("Expected the warning rate limit, got %+v", cfg.Notification.Levels["warning"].RateLimit)
+	}
+
+	if cfg.Notification.LogFile.MaxSizeMB != 5 {
+		t.Errorf("Expected the inlined log_file.max_size_mb, got %d", cfg.Notification.LogFile.MaxSizeMB)
+	}
+	if cfg.Server.Auth.TokenFile != "/etc/mcp-poke/tokens" {
+		t.Errorf("Expected the token file, got %q", cfg.Server.Auth.TokenFile)
+	}
+	if len(cfg.Notification.Schedule.QuietHours) != 1 || cfg.Notification.Schedule.QuietHours[0].End != "24:00" {
+		t.Errorf("Expected quiet hours from YAML, got %+v", cfg.Notification.Schedule.QuietHours)
+	}
+
+	if source := cfg.Source("notification.levels.error.icon"); source != "$MCP_POKE_LEVELS_ERROR_ICON" {
+		t.Errorf("Expected the variable as source, got %s", source)
+	}
+	if source := cfg.Source("notification.levels.error.urgency"); source != SourceDefault {
+		t.Errorf("Expected the default as source, got %s", source)
+	}
+}
+
+func TestApplyEnv_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable string
+		message  string
+	}{
+		{"unknown setting", "MCP_POKE_DRY_RUM=true", "did you mean $MCP_POKE_DRY_RUN?"},
+		{"invalid bool", "MCP_POKE_VERBOSE=maybe", "invalid value"},
+		{"invalid number", "MCP_POKE_ACTION_TIMEOUT=soon", "invalid value"},
+		{"unknown level setting", "MCP_POKE_LEVELS_ERROR_COLOR=red", "unknown setting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultConfig().applyEnv([]string{tt.variable})
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got: %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "notification:\n  default_level: warning\n  action_timeout: 60\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("MCP_POKE_ACTION_TIMEOUT", "90")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// The environment overrides the file, which overrides the defaults
+	if cfg.Notification.ActionTimeout != 90 {
+		t.Errorf("Expected action_timeout from the environment, got %d", cfg.Notification.ActionTimeout)
+	}
+	if cfg.Notification.DefaultLevel != "warning" {
+		t.Errorf("Expected default_level from the file, got %s", cfg.Notification.DefaultLevel)
+	}
+
+	sources := make(map[string]string)
+	for _, setting := range cfg.Settings() {
+		sources[setting.Path] = setting.Source
+	}
+	expected := map[string]string{
+		"notification.action_timeout": "$MCP_POKE_ACTION_TIMEOUT",
+		"notification.default_level":  configPath + ":2",
+		"notification.dry_run":        SourceDefault,
+	}
+	for path, source := range expected {
+		if sources[path] != source {
+			t.Errorf("Expected %s to come from %s, got %s", path, source, sources[path])
+		}
+	}
+}
+
+func TestLoadConfig_InvalidEnvValue(t *testing.T) {
+	t.Setenv("MCP_POKE_LEVELS_INFO_URGENCY", "urgent")
+
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "$MCP_POKE_LEVELS_INFO_URGENCY") {
+		t.Errorf("Expected the error to name the variable, got: %v", err)
+	}
+}
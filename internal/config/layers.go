@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the per-project configuration file, looked up in the
+// working directory and its parents
+const ProjectConfigName = ".mcp-poke.yaml"
+
+// Configuration layers, from the lowest precedence to the highest
+const (
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerProject = "project"
+)
+
+// projectKeys are the notification settings a project file may set. The
+// others run commands, send data elsewhere or write files, which a cloned
+// repository must not be able to do
+var projectKeys = []string{"template", "levels", "default_level", "action_timeout", "rate_limit", "batch", "schedule", "routes"}
+
+// Layer is a configuration file merged over the defaults and the layers before it
+type Layer struct {
+	// Name is LayerSystem, LayerUser or LayerProject
+	Name string
+	Path string
+}
+
+// Layers returns the configuration files in order of loading, later ones
+// overriding earlier ones: the system files, userPath and the project file.
+// Files that do not exist are skipped when loading
+func Layers(userPath string) []Layer {
+	var layers []Layer
+	for _, path := range systemConfigPaths() {
+		layers = append(layers, Layer{Name: LayerSystem, Path: path})
+	}
+	layers = append(layers, Layer{Name: LayerUser, Path: userPath})
+	if project := projectConfigPath(); project != "" {
+		layers = append(layers, Layer{Name: LayerProject, Path: project})
+	}
+
+	// A file is loaded once, as the last layer it appears in
+	var unique []Layer
+	for i, layer := range layers {
+		if !slices.ContainsFunc(layers[i+1:], func(l Layer) bool { return samePath(l.Path, layer.Path) }) {
+			unique = append(unique, layer)
+		}
+	}
+	return unique
+}
+
+// samePath reports whether a and b name the same file
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// systemConfigPaths returns the system-wide config files, the most important last
+func systemConfigPaths() []string {
+	if runtime.GOOS == "windows" {
+		// Windows: %ProgramData%\mcp-desktop-notification\config.yaml
+		if programData := os.Getenv("ProgramData"); programData != "" {
+			return []string{filepath.Join(programData, "mcp-desktop-notification", "config.yaml")}
+		}
+		return nil
+	}
+
+	// Linux/macOS: $XDG_CONFIG_DIRS lists the most important directory first
+	dirs := filepath.SplitList(os.Getenv("XDG_CONFIG_DIRS"))
+	if len(dirs) == 0 {
+		dirs = []string{"/etc/xdg"}
+	}
+	var paths []string
+	for _, dir := range slices.Backward(dirs) {
+		if dir != "" {
+			paths = append(paths, filepath.Join(dir, "mcp-desktop-notification", "config.yaml"))
+		}
+	}
+	return paths
+}
+
+// projectConfigPath returns the closest project file in the working directory
+// or its parents, or the one in the working directory if there is none so
+// that it is picked up once created
+func projectConfigPath() string {
+	dir := os.Getenv("PWD")
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return ""
+		}
+	}
+
+	for current := dir; ; {
+		candidate := filepath.Join(current, ProjectConfigName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return filepath.Join(dir, ProjectConfigName)
+		}
+		current = parent
+	}
+}
+
+// LoadLayers merges the existing files of layers over the defaults, in order,
+// then applies the MCP_POKE_* environment variables
+func LoadLayers(layers []Layer) (*Config, error) {
+	// Start with default config
+	cfg := DefaultConfig()
+
+	documents := make(map[string]*yaml.Node)
+	for _, layer := range layers {
+		document, err := cfg.loadFile(layer)
+		if err != nil {
+			return nil, err
+		}
+		if document != nil {
+			documents[layer.Path] = document
+		}
+	}
+
+	// The environment overrides the files
+	if err := cfg.applyEnv(os.Environ()); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	// Validate the config
+	if err := cfg.Validate(); err != nil {
+		return nil, cfg.locateError(err, layers, documents)
+	}
+
+	return cfg, nil
+}
+
+// locateError reports a validation error in the variable or file that set the
+// failing setting
+func (c *Config) locateError(err error, layers []Layer, documents map[string]*yaml.Node) error {
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	source := c.Source(fe.Path())
+	if strings.HasPrefix(source, "$") {
+		return fmt.Errorf("invalid configuration in %s: %w", source, err)
+	}
+	for path, document := range documents {
+		if strings.HasPrefix(source, path+":") {
+			return fmt.Errorf("invalid configuration in %s: %w", path, locate(document, err))
+		}
+	}
+
+	// A default or a flag, which the error is reported in the last file for
+	for _, layer := range slices.Backward(layers) {
+		if document, ok := documents[layer.Path]; ok {
+			return fmt.Errorf("invalid configuration in %s: %w", layer.Path, locate(document, err))
+		}
+	}
+	return fmt.Errorf("invalid configuration: %w", err)
+}
+
+// checkProjectKeys rejects the settings a project file may not set
+func checkProjectKeys(node *yaml.Node) []error {
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Value != "notification" {
+			errs = append(errs, &FieldError{Keys: []string{key.Value}, Line: key.Line, Column: key.Column, Err: errors.New("cannot be set in a project file")})
+			continue
+		}
+
+		notification := node.Content[i+1]
+		if notification.Kind == yaml.AliasNode {
+			notification = notification.Alias
+		}
+		for j := 0; j+1 < len(notification.Content); j += 2 {
+			setting := notification.Content[j]
+			if !slices.Contains(projectKeys, setting.Value) {
+				errs = append(errs, &FieldError{Keys: []string{"notification", setting.Value}, Line: setting.Line, Column: setting.Column, Err: errors.New("cannot be set in a project file")})
+			}
+		}
+	}
+	return errs
+}
+
+// merge decodes node over v, merging structs and the entries of maps of
+// structs key by key instead of replacing them as the decoder does. A null
+// entry removes the key from the map
+func merge(node *yaml.Node, v reflect.Value) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				// Merge keys bring in one mapping or a list of them
+				merged := []*yaml.Node{value}
+				if value.Kind == yaml.SequenceNode {
+					merged = value.Content
+				}
+				for _, m := range merged {
+					if err := merge(m, v); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if field := fieldByKey(v, key.Value); field.IsValid() {
+				if err := merge(value, field); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := reflect.ValueOf(node.Content[i].Value)
+			// A null entry removes the one set before, e.g. a built-in level
+			if value := node.Content[i+1]; value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+				v.SetMapIndex(key, reflect.Value{})
+				continue
+			}
+			// Map entries are not addressable, so merge into a copy
+			entry := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(key); existing.IsValid() {
+				entry.Set(existing)
+			}
+			if err := merge(node.Content[i+1], entry); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, entry)
+		}
+		return nil
+	default:
+		return node.Decode(v.Addr().Interface())
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to path, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// useLayers points the system directory and the working directory to
+// temporary ones and returns the layers of a user file there
+func useLayers(t *testing.T) (layers []Layer, system, user, project string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(dir, "etc"))
+	t.Setenv("ProgramData", filepath.Join(dir, "etc"))
+
+	// The project file is found from a subdirectory of the project
+	workDir := filepath.Join(dir, "repo", "src", "pkg")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	t.Setenv("PWD", workDir)
+
+	system = filepath.Join(dir, "etc", "mcp-desktop-notification", "config.yaml")
+	user = filepath.Join(dir, "home", "config.yaml")
+	project = filepath.Join(dir, "repo", ProjectConfigName)
+	writeFile(t, project, "# nothing configured yet\n")
+	return Layers(user), system, user, project
+}
+
+func TestLayers(t *testing.T) {
+	layers, system, user, project := useLayers(t)
+
+	expected := []Layer{
+		{Name: LayerSystem, Path: system},
+		{Name: LayerUser, Path: user},
+		{Name: LayerProject, Path: project},
+	}
+	if len(layers) != len(expected) {
+		t.Fatalf("Expected layers %v, got %v", expected, layers)
+	}
+	for i := range expected {
+		if layers[i] != expected[i] {
+			t.Errorf("Expected layers %v, got %v", expected, layers)
+		}
+	}
+
+	// Without a project file the one in the working directory is watched
+	if err := os.Remove(project); err != nil {
+		t.Fatalf("Failed to remove project file: %v", err)
+	}
+	layers = Layers(user)
+	if last := layers[len(layers)-1]; last.Path != filepath.Join(os.Getenv("PWD"), ProjectConfigName) {
+		t.Errorf("Expected the project file in the working directory, got %v", last)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	layers, system, user, project := useLayers(t)
+
+	writeFile(t, system, `
+notification:
+  action_timeout: 60
+  default_level: warning
+  levels:
+    error:
+      sound: ""
+`)
+	writeFile(t, user, `
+notification:
+  action_timeout: 90
+  template:
+    title: "{{.Title}}"
+`)
+	writeFile(t, project, `
+notification:
+  template:
+    title: "[repo] {{.Title}}"
+  levels:
+    error:
+      icon: dialog-warning
+`)
+
+	cfg, err := LoadLayers(layers)
+	if err != nil {
+		t.Fatalf("Failed to load layers: %v", err)
+	}
+
+	if cfg.Notification.ActionTimeout != 90 {
+		t.Errorf("Expected action_timeout from the user file, got %d", cfg.Notification.ActionTimeout)
+	}
+	if cfg.Notification.DefaultLevel != "warning" {
+		t.Errorf("Expected default_level from the system file, got %s", cfg.Notification.DefaultLevel)
+	}
+	if cfg.Notification.Template.Title != "[repo] {{.Title}}" {
+		t.Errorf("Expected the title from the project file, got %q", cfg.Notification.Template.Title)
+	}
+
+	// Level entries are merged setting by setting
+	errorLevel := cfg.Notification.Levels["error"]
+	if errorLevel.Icon != "dialog-warning" || errorLevel.Urgency != "critical" {
+		t.Errorf("Expected the project icon and the default urgency, got %+v", errorLevel)
+	}
+
+	expected := map[string]string{
+		"notification.action_timeout":       user + ":3",
+		"notification.default_level":        system + ":4",
+		"notification.template.title":       project + ":4",
+		"notification.levels.error.icon":    project + ":7",
+		"notification.levels.error.urgency": SourceDefault,
+	}
+	for path, source := range expected {
+		if got := cfg.Source(path); got != source {
+			t.Errorf("Expected %s to come from %s, got %s", path, source, got)
+		}
+	}
+}
+
+func TestLoadLayers_ProjectRestricted(t *testing.T) {
+	layers, _, _, project := useLayers(t)
+
+	// Settings that run commands or write files are left to the user
+	writeFile(t, project, `
+notification:
+  levels:
+    info:
+      icon: dialog-information
+  backends:
+    - name: pwn
+      type: command
+      command:
+        args: ["sh", "-c", "echo pwned"]
+server:
+  auth:
+    token_file: /tmp/tokens
+`)
+
+	_, err := LoadLayers(layers)
+	if err == nil {
+		t.Fatal("Expected error for settings a project file cannot set")
+	}
+	for _, expected := range []string{
+		project,
+		"line 6, column 3: notification.backends: cannot be set in a project file",
+		"line 11, column 1: server: cannot be set in a project file",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in error, got: %v", expected, err)
+		}
+	}
+}
+
+func TestLoadLayers_ErrorLocation(t *testing.T) {
+	layers, system, user, _ := useLayers(t)
+
+	writeFile(t, system, "notification:\n  levels:\n    info:\n      urgency: urgent\n")
+	writeFile(t, user, "notification:\n  verbose: true\n")
+
+	// The error is reported in the file that set the setting
+	_, err := LoadLayers(layers)
+	if err == nil || !strings.Contains(err.Error(), "invalid configuration in "+system+": line 4, column 16") {
+		t.Errorf("Expected the error located in the system file, got: %v", err)
+	}
+}
+
+func TestLoadLayers_RemoveLevel(t *testing.T) {
+	layers, system, user, _ := useLayers(t)
+
+	writeFile(t, system, `
+notification:
+  levels:
+    approval:
+      urgency: critical
+`)
+	// A null level removes a built-in one or one set by an earlier file
+	writeFile(t, user, `
+notification:
+  default_level: warning
+  levels:
+    info: ~
+    success: null
+    approval:
+`)
+
+	cfg, err := LoadLayers(layers)
+	if err != nil {
+		t.Fatalf("Failed to load layers: %v", err)
+	}
+	if names := cfg.LevelNames(); !slices.Equal(names, []string{"error", "warning"}) {
+		t.Errorf("Expected only the error and warning levels, got %v", names)
+	}
+	for _, setting := range cfg.Settings() {
+		if strings.HasPrefix(setting.Path, "notification.levels.info.") {
+			t.Errorf("Expected no settings of the removed level, got %s", setting.Path)
+		}
+	}
+
+	// The default level cannot be removed without picking another one
+	writeFile(t, user, "notification:\n  levels:\n    info: ~\n")
+	_, err = LoadLayers(layers)
+	if err == nil || !strings.Contains(err.Error(), `default_level: level "info" is not defined in levels`) {
+		t.Errorf("Expected an error for the removed default level, got: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceDefault is the source of settings left to their default value
+const SourceDefault = "default"
+
+// Setting is the effective value of a setting and where it was set
+type Setting struct {
+	// Path is the dotted path to the setting, e.g. notification.levels.error.icon
+	Path  string
+	Value string
+	// Source is a file and line, an environment variable such as
+	// $MCP_POKE_DRY_RUN, a command-line flag or SourceDefault
+	Source string
+}
+
+// SetSource records where the setting at keys was set, overriding earlier sources
+func (c *Config) SetSource(keys []string, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	path := keyPath(keys)
+	// The setting replaces whatever was set inside it
+	for recorded := range c.sources {
+		if strings.HasPrefix(recorded, path+".") || strings.HasPrefix(recorded, path+"[") {
+			delete(c.sources, recorded)
+		}
+	}
+	c.sources[path] = source
+}
+
+// Source returns where the setting at path was set. A setting inside a list
+// has the source of the list
+func (c *Config) Source(path string) string {
+	for {
+		if source, ok := c.sources[path]; ok {
+			return source
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i <= 0 {
+			return SourceDefault
+		}
+		path = path[:i]
+	}
+}
+
+// recordSources records the settings found in node, a document of file, as
+// set there
+func (c *Config) recordSources(node *yaml.Node, t reflect.Type, keys []string, file string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if field, ok := fields[node.Content[i].Value]; ok {
+				c.recordSources(node.Content[i+1], field, append(slices.Clone(keys), node.Content[i].Value), file)
+			}
+		}
+	case t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		// Entries are merged setting by setting
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c.recordSources(node.Content[i+1], t.Elem(), append(slices.Clone(keys), node.Content[i].Value), file)
+		}
+	default:
+		c.SetSource(keys, fmt.Sprintf("%s:%d", file, node.Line))
+	}
+}
+
+// Settings lists every setting with its effective value and source, in the
+// order of the configuration file
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	var walk func(v reflect.Value, keys []string)
+	walk = func(v reflect.Value, keys []string) {
+		switch {
+		case v.Kind() == reflect.Struct:
+			eachField(v, func(name string, field reflect.Value) {
+				walk(field, append(slices.Clone(keys), name))
+			})
+		case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Struct:
+			names := make([]string, 0, v.Len())
+			for _, key := range v.MapKeys() {
+				names = append(names, key.String())
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				walk(v.MapIndex(reflect.ValueOf(name)), append(slices.Clone(keys), name))
+			}
+		default:
+			path := keyPath(keys)
+			settings = append(settings, Setting{Path: path, Value: flowYAML(v.Interface()), Source: c.Source(path)})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), nil)
+	return settings
+}
+
+// eachField calls fn with the YAML key and value of each field of struct v,
+// in declaration order and looking into inlined structs
+func eachField(v reflect.Value, fn func(name string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(options, "inline") {
+			eachField(v.Field(i), fn)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fn(name, v.Field(i))
+	}
+}
+
+// flowYAML formats value as single-line YAML
+func flowYAML(value any) string {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	var flow func(n *yaml.Node)
+	flow = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+			n.Style = yaml.FlowStyle
+		}
+		for _, child := range n.Content {
+			flow(child)
+		}
+	}
+	flow(&node)
+	out, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(out))
+}
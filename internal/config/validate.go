@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a configuration error tied to a setting. When the setting
+// comes from a file, Line and Column locate it there
+type FieldError struct {
+	// Keys lead to the setting, e.g. notification, levels, info, urgency.
+	// List items are "[0]", "[1]" and so on
+	Keys   []string
+	Line   int
+	Column int
+	Err    error
+}
+
+// Path returns the dotted path to the setting, e.g. notification.backends[0].webhook.url
+func (e *FieldError) Path() string {
+	return keyPath(e.Keys)
+}
+
+// keyPath joins keys into a dotted path
+func keyPath(keys []string) string {
+	var path strings.Builder
+	for i, key := range keys {
+		if i > 0 && !strings.HasPrefix(key, "[") {
+			path.WriteString(".")
+		}
+		path.WriteString(key)
+	}
+	return path.String()
+}
+
+func (e *FieldError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %s: %v", e.Line, e.Column, e.Path(), e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path(), e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError ties err to the setting at keys, prepending them to the path when
+// err is already a FieldError
+func fieldError(err error, keys ...string) error {
+	if err == nil {
+		return nil
+	}
+	if fe, ok := err.(*FieldError); ok {
+		return &FieldError{Keys: append(keys, fe.Keys...), Err: fe.Err}
+	}
+	return &FieldError{Keys: keys, Err: err}
+}
+
+// fieldErrorf is fieldError with a formatted message for the setting key
+func fieldErrorf(key, format string, args ...any) error {
+	return fieldError(fmt.Errorf(format, args...), key)
+}
+
+// index is the key of the i-th item of a list
+func index(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+// locate sets the position of err, if it is a FieldError, to the setting in
+// the document. A setting missing from the document, because its value is a
+// default, is located at its closest parent
+func locate(document *yaml.Node, err error) error {
+	fe, ok := err.(*FieldError)
+	if !ok || document == nil || len(document.Content) == 0 {
+		return err
+	}
+
+	node, key := document.Content[0], (*yaml.Node)(nil)
+	for _, k := range fe.Keys {
+		child, childKey := lookup(node, k)
+		if child == nil {
+			break
+		}
+		node, key = child, childKey
+	}
+
+	// Point at the value of a scalar setting and at the key of a section
+	located := *fe
+	located.Line, located.Column = node.Line, node.Column
+	if key != nil && node.Kind != yaml.ScalarNode {
+		located.Line, located.Column = key.Line, key.Column
+	}
+	return &located
+}
+
+// lookup returns the value and key nodes of key in a mapping node, or the
+// "[i]" item of a sequence node
+func lookup(node *yaml.Node, key string) (value, keyNode *yaml.Node) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1], node.Content[i]
+			}
+		}
+	case yaml.SequenceNode:
+		var i int
+		if _, err := fmt.Sscanf(key, "[%d]", &i); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i], node.Content[i]
+		}
+	}
+	return nil, nil
+}
+
+// checkKeys reports the keys of node that do not match a setting of type t,
+// which are most likely typos the YAML decoder would silently ignore
+func checkKeys(node *yaml.Node, t reflect.Type, keys []string) []error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			// The decoder reports the type mismatch
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			path := append(append([]string(nil), keys...), key.Value)
+			field, ok := fields[key.Value]
+			if !ok {
+				errs = append(errs, &FieldError{Keys: path, Line: key.Line, Column: key.Column, Err: unknownKey(key.Value, fields)})
+				continue
+			}
+			errs = append(errs, checkKeys(value, field, path)...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := append(append([]string(nil), keys...), node.Content[i].Value)
+			errs = append(errs, checkKeys(node.Content[i+1], t.Elem(), path)...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			path := append(append([]string(nil), keys...), index(i))
+			errs = append(errs, checkKeys(item, t.Elem(), path)...)
+		}
+	}
+	return errs
+}
+
+// yamlFields maps the YAML keys of a struct to their types, following the
+// naming rules of the decoder
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(options, "inline") {
+			for key, typ := range yamlFields(field.Type) {
+				fields[key] = typ
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// unknownKey describes an unknown key, suggesting the closest known one
+func unknownKey(key string, fields map[string]reflect.Type) error {
+	if closest := closestKey(key, fields); closest != "" {
+		return fmt.Errorf("unknown key %q (did you mean %q?)", key, closest)
+	}
+	return fmt.Errorf("unknown key %q", key)
+}
+
+// closestKey returns the known key most likely meant instead of key, if any is close
+func closestKey[T any](key string, known map[string]T) string {
+	best, bestDistance := "", 3
+	for candidate := range known {
+		if d := editDistance(key, candidate); d < bestDistance || (d == bestDistance && best != "" && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// validatePath checks that a setting which is a file path, rather than a
+// theme name such as "dialog-warning", points to an existing file
+func validatePath(value string) error {
+	if !strings.ContainsRune(value, '/') && !strings.ContainsRune(value, os.PathSeparator) {
+		return nil
+	}
+	if _, err := os.Stat(value); os.IsNotExist(err) {
+		return fmt.Errorf("%s does not exist", value)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
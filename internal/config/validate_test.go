@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadYAML writes content to a config file and loads it
+func loadYAML(t *testing.T, content string) error {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	_, err := LoadConfig(configPath)
+	return err
+}
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	err := loadYAML(t, `
+notification:
+  verbos: true
+  levels:
+    info:
+      urgncy: low
+  log_file:
+    enabled: true
+    max_size_mb: 5
+`)
+	if err == nil {
+		t.Fatal("Expected error for unknown keys")
+	}
+
+	message := err.Error()
+	for _, expected := range []string{
+		`line 3, column 3: notification.verbos: unknown key "verbos" (did you mean "verbose"?)`,
+		`line 6, column 7: notification.levels.info.urgncy: unknown key "urgncy" (did you mean "urgency"?)`,
+	} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in error, got: %s", expected, message)
+		}
+	}
+	// Keys of inlined sections are known
+	if strings.Contains(message, "max_size_mb") {
+		t.Errorf("Expected inlined log_file keys to be accepted, got: %s", message)
+	}
+}
+
+func TestLoadConfig_ErrorLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		path    string
+		line    int
+		column  int
+		message string
+	}{
+		{
+			name:    "urgency",
+			yaml:    "notification:\n  levels:\n    info:\n      urgency: urgent\n",
+			path:    "notification.levels.info.urgency",
+			line:    4,
+			column:  16,
+			message: "unknown urgency",
+		},
+		{
+			name:    "missing icon file",
+			yaml:    "notification:\n  levels:\n    info:\n      icon: /nonexistent/icon.png\n",
+			path:    "notification.levels.info.icon",
+			line:    4,
+			column:  13,
+			message: "does not exist",
+		},
+		{
+			name:    "unparsable template",
+			yaml:    "notification:\n  template:\n    title: \"{{.Title\"\n",
+			path:    "notification.template.title",
+			line:    3,
+			column:  12,
+			message: "unclosed action",
+		},
+		{
+			name:    "webhook url",
+			yaml:    "notification:\n  backends:\n    - name: hook\n      type: webhook\n      webhook:\n        url: ftp://example.com\n",
+			path:    "notification.backends[0].webhook.url",
+			line:    6,
+			column:  14,
+			message: "http or https",
+		},
+		{
+			// Not in the file, so located at the closest parent
+			name:    "token missing",
+			yaml:    "server:\n  auth:\n    tokens:\n      - name: ci\n",
+			path:    "server.auth.tokens[0].token",
+			line:    4,
+			column:  9,
+			message: "cannot be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadYAML(t, tt.yaml)
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				t.Fatalf("Expected a FieldError, got: %v", err)
+			}
+			if fe.Path() != tt.path {
+				t.Errorf("Expected path %s, got %s", tt.path, fe.Path())
+			}
+			if fe.Line != tt.line || fe.Column != tt.column {
+				t.Errorf("Expected line %d, column %d, got line %d, column %d", tt.line, tt.column, fe.Line, fe.Column)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected %q in error, got: %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Level(t *testing.T) {
+	icon := filepath.Join(t.TempDir(), "icon.png")
+	if err := os.WriteFile(icon, nil, 0644); err != nil {
+		t.Fatalf("Failed to create icon: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		level Level
+		valid bool
+	}{
+		{"theme icon", Level{Urgency: "normal", Icon: "dialog-warning"}, true},
+		{"existing icon file", Level{Urgency: "low", Icon: icon}, true},
+		{"missing icon file", Level{Icon: filepath.Join(filepath.Dir(icon), "missing.png")}, false},
+		{"missing sound file", Level{Sound: "/nonexistent/sound.oga"}, false},
+		{"unknown urgency", Level{Urgency: "high"}, false},
+		{"template with known fields", Level{Template: Template{Body: "{{.Message}} from {{.Client.Name}} at {{.Timestamp.Format \"15:04\"}}"}}, true},
+		{"template with unknown field", Level{Template: Template{Body: "{{.Client.Nmae}}"}}, true},
+		{"unparsable template", Level{Template: Template{Body: "{{.Message"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Notification.Levels["custom"] = tt.level
+			err := cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid level, got: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error for invalid level")
+			}
+		})
+	}
+}
+
+func TestLoadConfig_EmptyFile(t *testing.T) {
+	if err := loadYAML(t, "# nothing configured yet\n"); err != nil {
+		t.Errorf("Expected an empty file to load the defaults, got: %v", err)
+	}
+}
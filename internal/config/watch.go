@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// fileState is what Watch compares to notice a change
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func (f fileState) equal(other fileState) bool {
+	return f.exists == other.exists && f.size == other.size && f.modTime.Equal(other.modTime)
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// Watch checks path every interval and calls changed when the file is
+// created, modified or removed, until ctx is done. Polling follows editors that
+// replace the file instead of writing it, and needs no platform support
+func Watch(ctx context.Context, path string, interval time.Duration, changed func()) {
+	last := statFile(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := statFile(path)
+			if !current.equal(last) {
+				last = current
+				changed()
+			}
+		}
+	}
+}
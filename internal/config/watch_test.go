@@ -0,0 +1,50 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 4)
+	go Watch(ctx, path, 10*time.Millisecond, func() { changes <- struct{}{} })
+
+	expectChange := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a change when the file is %s", what)
+		}
+	}
+
+	// Let the watcher record the missing file first
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("notification:\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	expectChange("created")
+
+	if err := os.WriteFile(path, []byte("notification:\n  verbose: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	expectChange("modified")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	expectChange("removed")
+
+	select {
+	case <-changes:
+		t.Error("Expected no change without a file operation")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
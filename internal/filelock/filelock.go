@@ -0,0 +1,23 @@
+// Package filelock takes advisory locks on files shared between processes,
+// such as several servers writing the same history
+package filelock
+
+import (
+	"fmt"
+	"os"
+)
+
+// Lock opens the lock file at path, creating it if needed, and locks it
+// exclusively, waiting for other processes to release it. Closing the returned
+// file releases the lock
+func Lock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return f, nil
+}
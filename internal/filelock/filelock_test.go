@@ -0,0 +1,45 @@
+package filelock
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLock_Exclusive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Files are not locked on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "history.jsonl.lock")
+	first, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	// A second lock, as taken by another process, waits for the first
+	locked := make(chan struct{})
+	go func() {
+		second, err := Lock(path)
+		if err != nil {
+			t.Errorf("Failed to lock: %v", err)
+			return
+		}
+		close(locked)
+		second.Close()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("Expected the second lock to wait for the first")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the second lock once the first is released")
+	}
+}
@@ -0,0 +1,9 @@
+package filelock
+
+import "os"
+
+// lock does nothing: the standard library has no file locking on Windows, so
+// processes sharing a file there are not kept apart
+func lock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,267 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/filelock"
+)
+
+// Delivery statuses recorded for each entry. Notifications that were not
+// delivered on purpose record the reason instead, e.g. "rate limited"
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+)
+
+// Entry is a single notification recorded in the history
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Level     string    `json:"level"`
+	Workspace string    `json:"workspace,omitempty"`
+	Client    string    `json:"client,omitempty"`
+	Actions   []string  `json:"actions,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Filter selects entries when listing the history. Zero values match everything
+type Filter struct {
+	Level     string
+	Workspace string
+	Since     time.Time
+	Until     time.Time
+	// Limit keeps only the most recent entries when positive
+	Limit int
+}
+
+// matches reports whether the entry passes the filter
+func (f Filter) matches(e Entry) bool {
+	if f.Level != "" && e.Level != f.Level {
+		return false
+	}
+	if f.Workspace != "" && e.Workspace != f.Workspace {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Store is an append-only JSON lines notification history with retention
+// limits. Several processes may share the file: appending and compacting take
+// a lock on path.lock
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	maxEntries int
+	maxAge     time.Duration
+	// count is the number of lines in the file, used to decide when to compact
+	count int
+	now   func() time.Time
+}
+
+// Open opens the history file at path, creating its directory if needed and
+// applying the retention limits. A zero maxEntries or maxAge disables that limit
+func Open(path string, maxEntries int, maxAge time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	s := &Store{
+		path:       path,
+		maxEntries: maxEntries,
+		maxAge:     maxAge,
+		now:        time.Now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Path returns the location of the history file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append records an entry, compacting the file once it grows past the retention limit
+func (s *Store) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history entry: %w", err)
+	}
+
+	s.count++
+	// Allow some slack over the limit so the file is not rewritten on every append
+	if s.maxEntries > 0 && s.count > s.maxEntries+s.maxEntries/10 {
+		return s.compact()
+	}
+	return nil
+}
+
+// List returns the entries matching the filter, oldest first
+func (s *Store) List(filter Filter) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := s.cutoff()
+	var matched []Entry
+	for _, e := range entries {
+		if e.Timestamp.Before(cutoff) || !filter.matches(e) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[len(matched)-filter.Limit:]
+	}
+	return matched, nil
+}
+
+// lock keeps other processes from appending to or compacting the file until
+// the returned function is called
+func (s *Store) lock() (func(), error) {
+	f, err := filelock.Lock(s.path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock history file: %w", err)
+	}
+	return func() { f.Close() }, nil
+}
+
+// cutoff returns the oldest timestamp kept by the age limit
+func (s *Store) cutoff() time.Time {
+	if s.maxAge <= 0 {
+		return time.Time{}
+	}
+	return s.now().Add(-s.maxAge)
+}
+
+// read loads all entries from the file, skipping lines that cannot be decoded
+func (s *Store) read() ([]Entry, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return entries, nil
+}
+
+// compact rewrites the file keeping only entries within the retention limits.
+// The caller holds the lock
+func (s *Store) compact() error {
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	cutoff := s.cutoff()
+	kept := entries[:0]
+	for _, e := range entries {
+		if !e.Timestamp.Before(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	if s.maxEntries > 0 && len(kept) > s.maxEntries {
+		kept = kept[len(kept)-s.maxEntries:]
+	}
+
+	if len(kept) == len(entries) {
+		s.count = len(entries)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, e := range kept {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode history entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	// Write to a temporary file and rename so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace history file: %w", err)
+	}
+
+	s.count = len(kept)
+	return nil
+}
@@ -0,0 +1,261 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T, maxEntries int, maxAge time.Duration) *Store {
+	t.Helper()
+
+	store, err := Open(filepath.Join(t.TempDir(), "state", "history.jsonl"), maxEntries, maxAge)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	return store
+}
+
+func TestOpen_CreatesDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "history.jsonl")
+
+	store, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	if store.Path() != path {
+		t.Errorf("Expected path %s, got %s", path, store.Path())
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		t.Errorf("Expected history directory to be created: %v", err)
+	}
+}
+
+func TestStore_AppendAndList(t *testing.T) {
+	store := openTestStore(t, 0, 0)
+
+	base := time.Now().Add(-time.Hour)
+	entries := []Entry{
+		{Timestamp: base, Title: "Build", Message: "passed", Level: "success", Workspace: "projects/a", Status: StatusSent},
+		{Timestamp: base.Add(10 * time.Minute), Title: "Deploy", Message: "failed", Level: "error", Workspace: "projects/b", Status: StatusFailed, Error: "boom"},
+		{Timestamp: base.Add(20 * time.Minute), Title: "Tests", Message: "flaky", Level: "warning", Workspace: "projects/a", Status: StatusSent},
+	}
+	for _, e := range entries {
+		if err := store.Append(e); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	all, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(all))
+	}
+	if all[0].Title != "Build" || all[2].Title != "Tests" {
+		t.Errorf("Expected entries oldest first, got %s ... %s", all[0].Title, all[2].Title)
+	}
+	if all[1].Error != "boom" || all[1].Status != StatusFailed {
+		t.Errorf("Expected failure details to round-trip, got %+v", all[1])
+	}
+}
+
+func TestStore_ListFilters(t *testing.T) {
+	store := openTestStore(t, 0, 0)
+
+	base := time.Now().Add(-time.Hour)
+	for i, level := range []string{"info", "error", "info", "warning"} {
+		workspace := "projects/a"
+		if i%2 == 1 {
+			workspace = "projects/b"
+		}
+		store.Append(Entry{
+			Timestamp: base.Add(time.Duration(i) * 10 * time.Minute),
+			Title:     level,
+			Level:     level,
+			Workspace: workspace,
+			Status:    StatusSent,
+		})
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected int
+	}{
+		{"level", Filter{Level: "info"}, 2},
+		{"workspace", Filter{Workspace: "projects/b"}, 2},
+		{"level and workspace", Filter{Level: "info", Workspace: "projects/b"}, 0},
+		{"since", Filter{Since: base.Add(15 * time.Minute)}, 2},
+		{"until", Filter{Until: base.Add(15 * time.Minute)}, 2},
+		{"range", Filter{Since: base.Add(5 * time.Minute), Until: base.Add(25 * time.Minute)}, 2},
+		{"limit", Filter{Limit: 3}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(entries) != tt.expected {
+				t.Errorf("Expected %d entries, got %d", tt.expected, len(entries))
+			}
+		})
+	}
+
+	// Limit keeps the most recent entries
+	entries, _ := store.List(Filter{Limit: 1})
+	if len(entries) != 1 || entries[0].Level != "warning" {
+		t.Errorf("Expected the most recent entry, got %+v", entries)
+	}
+}
+
+func TestStore_MaxEntries(t *testing.T) {
+	store := openTestStore(t, 10, 0)
+
+	for i := 0; i < 25; i++ {
+		if err := store.Append(Entry{Timestamp: time.Now(), Title: "n", Message: strings.Repeat("x", i), Status: StatusSent}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	entries, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	// Compaction allows 10% slack over the limit
+	if len(entries) > 11 {
+		t.Errorf("Expected at most 11 entries after compaction, got %d", len(entries))
+	}
+	if last := entries[len(entries)-1]; len(last.Message) != 24 {
+		t.Errorf("Expected the newest entry to be kept, got message length %d", len(last.Message))
+	}
+
+	// Reopening compacts down to the exact limit
+	reopened, err := Open(store.Path(), 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	entries, _ = reopened.List(Filter{})
+	if len(entries) != 10 {
+		t.Errorf("Expected 10 entries after reopening, got %d", len(entries))
+	}
+}
+
+func TestStore_MaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := Open(path, 0, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	store.Append(Entry{Timestamp: time.Now().Add(-48 * time.Hour), Title: "old", Status: StatusSent})
+	store.Append(Entry{Timestamp: time.Now(), Title: "new", Status: StatusSent})
+
+	entries, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Title != "new" {
+		t.Errorf("Expected only the recent entry, got %+v", entries)
+	}
+
+	// Reopening drops expired entries from the file
+	if _, err := Open(path, 0, 24*time.Hour); err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read history file: %v", err)
+	}
+	if strings.Contains(string(data), `"old"`) {
+		t.Error("Expected expired entry to be removed from the file")
+	}
+}
+
+func TestStore_SkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `{"timestamp":"2025-01-01T10:00:00Z","title":"ok","level":"info","status":"sent"}
+not json
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write history file: %v", err)
+	}
+
+	store, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	entries, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Title != "ok" {
+		t.Errorf("Expected the valid entry only, got %+v", entries)
+	}
+}
+
+func TestStore_SharedBetweenProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// Two stores on the same file stand for two servers. One keeps appending
+	// while the other compacts, which must not lose the appended entries
+	writer, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			compacting, err := Open(path, 0, time.Hour)
+			if err != nil {
+				t.Errorf("Failed to open store: %v", err)
+				return
+			}
+			compacting.Append(Entry{Timestamp: time.Now().Add(-2 * time.Hour), Title: "expired", Status: StatusSent})
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		if err := writer.Append(Entry{Timestamp: time.Now(), Title: fmt.Sprint(i), Status: StatusSent}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	entries, err := writer.List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	kept := 0
+	for _, e := range entries {
+		if e.Title != "expired" {
+			kept++
+		}
+	}
+	if kept != 1000 {
+		t.Errorf("Expected all 1000 appended entries, got %d", kept)
+	}
+
+	// No temporary files are left behind
+	tmps, _ := filepath.Glob(path + ".*.tmp")
+	if len(tmps) != 0 {
+		t.Errorf("Expected no temporary files, got %v", tmps)
+	}
+}
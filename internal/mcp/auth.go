@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// tokenNameKey is the TokenInfo.Extra key holding the name of the token a request used
+const tokenNameKey = "name"
+
+// tokenVerifier accepts the configured tokens, recording the token's name in the TokenInfo
+func tokenVerifier(tokens []config.Token) auth.TokenVerifier {
+	return func(ctx context.Context, token string, req *http.Request) (*auth.TokenInfo, error) {
+		for _, t := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+				return &auth.TokenInfo{
+					// Configured tokens do not expire, but the middleware requires an expiration
+					Expiration: time.Now().Add(time.Hour),
+					Extra:      map[string]any{tokenNameKey: t.Name},
+				}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown token: %w", auth.ErrInvalidToken)
+	}
+}
+
+// requireToken wraps handler so that only requests with a configured bearer
+// token are served. Unknown or missing tokens get 401 Unauthorized
+func requireToken(tokens []config.Token, handler http.Handler) http.Handler {
+	return auth.RequireBearerToken(tokenVerifier(tokens), nil)(handler)
+}
+
+// serverForRequest returns the MCP server for the token the request was
+// authenticated with, so notifications are shown under the token's name.
+// Unauthenticated requests get the default server
+func (s *Server) serverForRequest(req *http.Request) *mcp.Server {
+	info := auth.TokenInfoFromContext(req.Context())
+	if info == nil {
+		return s.defaultServer()
+	}
+	name, _ := info.Extra[tokenNameKey].(string)
+	return s.serverFor(name)
+}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// bearerTransport adds a bearer token to every request
+type bearerTransport struct {
+	token string
+}
+
+func (b *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newAuthTestServer serves the poke tool over HTTP, accepting the token "s3cret" named "devbox"
+func newAuthTestServer(t *testing.T, transport string) (*httptest.Server, *recordingNotifier) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.Server.Auth.Tokens = []config.Token{{Name: "devbox", Token: "s3cret"}}
+	noti := &recordingNotifier{}
+	server := NewServer(cfg, noti)
+	if err := server.init(); err != nil {
+		t.Fatalf("Failed to initialize server: %v", err)
+	}
+	handler, err := server.httpHandler(transport, defaultHTTPAddr)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+	return httpServer, noti
+}
+
+func TestAuth_RejectsUnknownTokens(t *testing.T) {
+	httpServer, _ := newAuthTestServer(t, TransportHTTP)
+
+	for _, header := range []string{"", "Bearer wrong", "Basic czNjcmV0"} {
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{}`))
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected 401, got %d", header, resp.StatusCode)
+		}
+	}
+}
+
+func TestAuth_TokenNameBecomesWorkspace(t *testing.T) {
+	tests := []struct {
+		transport string
+		client    func(url string, httpClient *http.Client) mcp.Transport
+	}{
+		{TransportHTTP, func(url string, httpClient *http.Client) mcp.Transport {
+			return &mcp.StreamableClientTransport{Endpoint: url, HTTPClient: httpClient}
+		}},
+		{TransportSSE, func(url string, httpClient *http.Client) mcp.Transport {
+			return &mcp.SSEClientTransport{Endpoint: url, HTTPClient: httpClient}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transport, func(t *testing.T) {
+			httpServer, noti := newAuthTestServer(t, tt.transport)
+
+			ctx := context.Background()
+			httpClient := &http.Client{Transport: &bearerTransport{token: "s3cret"}}
+			client := mcp.NewClient(&mcp.Implementation{Name: "remote-agent", Version: "0.1.0"}, nil)
+			session, err := client.Connect(ctx, tt.client(httpServer.URL, httpClient), nil)
+			if err != nil {
+				t.Fatalf("Failed to connect with a valid token: %v", err)
+			}
+			defer session.Close()
+
+			if _, err := session.CallTool(ctx, &mcp.CallToolParams{
+				Name:      "poke",
+				Arguments: map[string]any{"message": "Tests passed"},
+			}); err != nil {
+				t.Fatalf("poke failed: %v", err)
+			}
+			if noti.workspace != "devbox" {
+				t.Errorf("Expected workspace 'devbox' from the token name, got %q", noti.workspace)
+			}
+		})
+	}
+}
+
+func TestAuth_TokenFileError(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.Auth.TokenFile = "/nonexistent/tokens"
+	server := NewServer(cfg, &recordingNotifier{})
+	if err := server.init(); err != nil {
+		t.Fatalf("Failed to initialize server: %v", err)
+	}
+	if _, err := server.httpHandler(TransportHTTP, defaultHTTPAddr); err == nil {
+		t.Error("Expected error for a missing token file")
+	}
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxIssuedIDs is how many of the latest notification ids each workspace can dismiss
+const maxIssuedIDs = 100
+
+// DismissArgs represents the arguments for the dismiss tool
+type DismissArgs struct {
+	ID string `json:"id" jsonschema:"The id returned by poke for the notification to close"`
+}
+
+// issuedIDs remembers the notification ids poke returned to each workspace.
+// Daemon ids are global, so without it a workspace could close the
+// notifications of other workspaces or applications
+type issuedIDs struct {
+	mu  sync.Mutex
+	ids map[string][]string
+}
+
+// add records id as issued to workspace, forgetting the oldest ids past maxIssuedIDs
+func (i *issuedIDs) add(workspace, id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.ids == nil {
+		i.ids = make(map[string][]string)
+	}
+	ids := append(i.ids[workspace], id)
+	if len(ids) > maxIssuedIDs {
+		ids = ids[len(ids)-maxIssuedIDs:]
+	}
+	i.ids[workspace] = ids
+}
+
+// take reports whether id was issued to workspace, forgetting it
+func (i *issuedIDs) take(workspace, id string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	index := slices.Index(i.ids[workspace], id)
+	if index < 0 {
+		return false
+	}
+	i.ids[workspace] = slices.Delete(i.ids[workspace], index, index+1)
+	return true
+}
+
+// registerDismissToolHandler registers the dismiss tool with the MCP server
+func (s *Server) registerDismissToolHandler(srv *mcp.Server, workspace string) {
+	handler := s.handleDismissTool
+	if workspace != "" {
+		handler = func(ctx context.Context, req *mcp.CallToolRequest, args DismissArgs) (*mcp.CallToolResult, any, error) {
+			return s.dismiss(ctx, args, workspace)
+		}
+	}
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "dismiss",
+		Description: "Close a notification previously sent with poke, using the id poke returned. Use it to retract a notification that is no longer relevant, e.g. a request for approval the user already answered in the terminal.",
+	}, handler)
+}
+
+// handleDismissTool handles the dismiss tool invocation
+func (s *Server) handleDismissTool(ctx context.Context, req *mcp.CallToolRequest, args DismissArgs) (*mcp.CallToolResult, any, error) {
+	return s.dismiss(ctx, args, notifier.AppName())
+}
+
+// dismiss closes the notification identified by the id poke returned to workspace
+func (s *Server) dismiss(ctx context.Context, args DismissArgs, workspace string) (*mcp.CallToolResult, any, error) {
+	if args.ID == "" {
+		return nil, nil, fmt.Errorf("id cannot be empty")
+	}
+
+	cfg, noti := s.state()
+	if cfg.Notification.Verbose {
+		log.Printf("[MCP Server] Dismissing notification %s", args.ID)
+	}
+
+	// Workspaces cannot close each other's notifications
+	if !s.issued.take(workspace, args.ID) {
+		return nil, nil, fmt.Errorf("unknown notification: %s (only ids returned by poke can be dismissed)", args.ID)
+	}
+
+	err := notifier.Close(ctx, noti, args.ID)
+	if errors.Is(err, notifier.ErrNotSupported) {
+		return textResult(fmt.Sprintf("Notification %s not dismissed: the notification backend cannot close notifications", args.ID)), nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dismiss notification: %w", err)
+	}
+	return textResult(fmt.Sprintf("Notification %s dismissed", args.ID)), nil, nil
+}
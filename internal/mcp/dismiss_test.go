@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+)
+
+func TestHandleDismissTool(t *testing.T) {
+	noti := &updatingNotifier{recordingNotifier: recordingNotifier{receipt: notifier.Receipt{ID: "42"}}}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	result, _, err := server.handlePokeTool(context.Background(), nil, PokeArgs{Message: "Approve the migration?"})
+	if err != nil {
+		t.Fatalf("poke failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "id 42") {
+		t.Errorf("Expected the poke result to include the id, got %q", text)
+	}
+
+	result, _, err = server.handleDismissTool(context.Background(), nil, DismissArgs{ID: "42"})
+	if err != nil {
+		t.Fatalf("dismiss failed: %v", err)
+	}
+	if text := resultText(t, result); text != "Notification 42 dismissed" {
+		t.Errorf("Unexpected result: %q", text)
+	}
+	if len(noti.closed) != 1 || noti.closed[0] != "42" {
+		t.Errorf("Expected notification 42 to be closed, got %v", noti.closed)
+	}
+}
+
+func TestHandleDismissTool_OtherWorkspace(t *testing.T) {
+	noti := &updatingNotifier{recordingNotifier: recordingNotifier{receipt: notifier.Receipt{ID: "42"}}}
+	server := NewServer(config.DefaultConfig(), noti)
+	ctx := context.Background()
+
+	if _, _, err := server.poke(ctx, nil, PokeArgs{Message: "Approve the migration?"}, "projects/foo"); err != nil {
+		t.Fatalf("poke failed: %v", err)
+	}
+
+	// Daemon ids are global, so only the workspace poke returned the id to can use it
+	if _, _, err := server.dismiss(ctx, DismissArgs{ID: "42"}, "projects/bar"); err == nil {
+		t.Error("Expected error when dismissing another workspace's notification")
+	}
+	if _, _, err := server.dismiss(ctx, DismissArgs{ID: "7"}, "projects/foo"); err == nil {
+		t.Error("Expected error when dismissing an id poke never returned")
+	}
+	if len(noti.closed) != 0 {
+		t.Errorf("Expected nothing to be closed, got %v", noti.closed)
+	}
+
+	if _, _, err := server.dismiss(ctx, DismissArgs{ID: "42"}, "projects/foo"); err != nil {
+		t.Errorf("Expected the workspace to dismiss its own notification, got: %v", err)
+	}
+}
+
+func TestHandleDismissTool_UnsupportedBackend(t *testing.T) {
+	server := NewServer(config.DefaultConfig(), &recordingNotifier{receipt: notifier.Receipt{ID: "42"}})
+
+	if _, _, err := server.handlePokeTool(context.Background(), nil, PokeArgs{Message: "Approve the migration?"}); err != nil {
+		t.Fatalf("poke failed: %v", err)
+	}
+
+	result, _, err := server.handleDismissTool(context.Background(), nil, DismissArgs{ID: "42"})
+	if err != nil {
+		t.Fatalf("dismiss failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "cannot close notifications") {
+		t.Errorf("Expected the result to say the backend cannot close notifications, got %q", text)
+	}
+
+	if _, _, err := server.handleDismissTool(context.Background(), nil, DismissArgs{}); err == nil {
+		t.Error("Expected error for an empty id")
+	}
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// historyResourceURI is the MCP resource exposing the notification history
+const historyResourceURI = "notifications://history"
+
+// errHistoryDisabled is returned by history requests made when the history is disabled
+var errHistoryDisabled = errors.New("notification history is disabled")
+
+// ListNotificationsArgs represents the arguments for the list_notifications tool
+type ListNotificationsArgs struct {
+	Level     string `json:"level,omitempty" jsonschema:"Only return notifications with this severity level"`
+	Workspace string `json:"workspace,omitempty" jsonschema:"Only return notifications from this workspace (e.g. projects/my-app)"`
+	Since     string `json:"since,omitempty" jsonschema:"Only return notifications sent at or after this time: RFC 3339 timestamp, or a duration such as 2h meaning that long ago"`
+	Until     string `json:"until,omitempty" jsonschema:"Only return notifications sent at or before this time: RFC 3339 timestamp, or a duration such as 30m meaning that long ago"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of most recent notifications to return"`
+}
+
+// SetHistory enables the history resource and list_notifications tool backed by store
+func (s *Server) SetHistory(store *history.Store) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.history = store
+}
+
+// registerHistoryHandlers registers the history resource and list_notifications tool
+func (s *Server) registerHistoryHandlers(srv *mcp.Server) {
+	srv.AddResource(&mcp.Resource{
+		URI:         historyResourceURI,
+		Name:        "notification-history",
+		Description: "Notifications sent by this server, oldest first, as a JSON array",
+		MIMEType:    "application/json",
+	}, s.handleHistoryResource)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "list_notifications",
+		Description: "List previously sent notifications, optionally filtered by level, workspace and time range.",
+	}, s.handleListNotificationsTool)
+}
+
+// handleHistoryResource returns the whole retained history
+func (s *Server) handleHistoryResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	// A reload may have disabled the history while the request was in flight
+	store := s.historyStore()
+	if store == nil {
+		return nil, errHistoryDisabled
+	}
+
+	entries, err := store.List(history.Filter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	data, err := marshalEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: historyResourceURI, MIMEType: "application/json", Text: data},
+		},
+	}, nil
+}
+
+// handleListNotificationsTool returns the history entries matching the filters
+func (s *Server) handleListNotificationsTool(ctx context.Context, req *mcp.CallToolRequest, args ListNotificationsArgs) (*mcp.CallToolResult, any, error) {
+	filter, err := historyFilter(args, time.Now())
+	if err != nil {
+		if s.verbose() {
+			log.Printf("[MCP Server] Parameter validation error: %v", err)
+		}
+		return nil, nil, err
+	}
+
+	store := s.historyStore()
+	if store == nil {
+		return nil, nil, errHistoryDisabled
+	}
+
+	entries, err := store.List(filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	data, err := marshalEntries(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: data},
+		},
+	}, nil, nil
+}
+
+// historyFilter converts the tool arguments into a history filter
+func historyFilter(args ListNotificationsArgs, now time.Time) (history.Filter, error) {
+	if args.Limit < 0 {
+		return history.Filter{}, fmt.Errorf("limit cannot be negative")
+	}
+
+	since, err := parseTimeBound(args.Since, now)
+	if err != nil {
+		return history.Filter{}, fmt.Errorf("invalid since: %w", err)
+	}
+	until, err := parseTimeBound(args.Until, now)
+	if err != nil {
+		return history.Filter{}, fmt.Errorf("invalid until: %w", err)
+	}
+
+	return history.Filter{
+		Level:     args.Level,
+		Workspace: args.Workspace,
+		Since:     since,
+		Until:     until,
+		Limit:     args.Limit,
+	}, nil
+}
+
+// parseTimeBound parses an RFC 3339 timestamp, or a duration meaning that long before now
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a duration", value)
+	}
+	return now.Add(-d), nil
+}
+
+// marshalEntries encodes entries as an indented JSON array
+func marshalEntries(entries []history.Entry) (string, error) {
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode history: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectTestClient initializes server and connects an in-memory MCP client to it
+func connectTestClient(t *testing.T, server *Server) *mcp.ClientSession {
+	t.Helper()
+
+	if err := server.init(); err != nil {
+		t.Fatalf("Failed to initialize server: %v", err)
+	}
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.defaultServer().Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "0.1.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	return session
+}
+
+func newHistoryServer(t *testing.T) (*Server, *history.Store) {
+	t.Helper()
+
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	server := NewServer(cfg, notifier.NewHistoryNotifier(cfg, &recordingNotifier{}, store))
+	server.SetHistory(store)
+	return server, store
+}
+
+func TestHistory_PokeIsRecordedAndListed(t *testing.T) {
+	server, _ := newHistoryServer(t)
+	session := connectTestClient(t, server)
+	ctx := context.Background()
+
+	for _, args := range []map[string]any{
+		{"message": "Build passed", "level": "success"},
+		{"message": "Deploy failed", "level": "error"},
+	} {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "poke", Arguments: args})
+		if err != nil || result.IsError {
+			t.Fatalf("poke failed: %v %+v", err, result)
+		}
+	}
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_notifications",
+		Arguments: map[string]any{"level": "error"},
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("list_notifications failed: %v %+v", err, result)
+	}
+
+	var entries []history.Entry
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &entries); err != nil {
+		t.Fatalf("Failed to decode list_notifications result: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "Deploy failed" {
+		t.Fatalf("Expected only the error notification, got %+v", entries)
+	}
+	if entries[0].Client != "test-client" {
+		t.Errorf("Expected client name to be recorded, got %q", entries[0].Client)
+	}
+	if entries[0].Workspace != notifier.AppName() {
+		t.Errorf("Expected workspace %q, got %q", notifier.AppName(), entries[0].Workspace)
+	}
+
+	resource, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: historyResourceURI})
+	if err != nil {
+		t.Fatalf("Failed to read history resource: %v", err)
+	}
+	if err := json.Unmarshal([]byte(resource.Contents[0].Text), &entries); err != nil {
+		t.Fatalf("Failed to decode history resource: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries in the history resource, got %d", len(entries))
+	}
+}
+
+func TestHistory_NotRegisteredWithoutStore(t *testing.T) {
+	server := NewServer(config.DefaultConfig(), &recordingNotifier{})
+	session := connectTestClient(t, server)
+
+	tools, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to list tools: %v", err)
+	}
+	for _, tool := range tools.Tools {
+		if tool.Name == "list_notifications" {
+			t.Error("Expected list_notifications to be absent without a history store")
+		}
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	filter, err := historyFilter(ListNotificationsArgs{
+		Level:     "error",
+		Workspace: "projects/a",
+		Since:     "2h",
+		Until:     "2025-06-01T11:30:00Z",
+		Limit:     5,
+	}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !filter.Since.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("Expected since two hours ago, got %v", filter.Since)
+	}
+	if !filter.Until.Equal(time.Date(2025, 6, 1, 11, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected until: %v", filter.Until)
+	}
+	if filter.Level != "error" || filter.Workspace != "projects/a" || filter.Limit != 5 {
+		t.Errorf("Unexpected filter: %+v", filter)
+	}
+
+	if _, err := historyFilter(ListNotificationsArgs{Since: "yesterday"}, now); err == nil {
+		t.Error("Expected error for unparsable since")
+	}
+	if _, err := historyFilter(ListNotificationsArgs{Limit: -1}, now); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+}
+
+func TestHistory_DisabledWhileInFlight(t *testing.T) {
+	cfg := config.DefaultConfig()
+	server := NewServer(cfg, &recordingNotifier{})
+
+	// A request registered before a reload disabled the history fails cleanly
+	if _, _, err := server.handleListNotificationsTool(context.Background(), nil, ListNotificationsArgs{}); err != errHistoryDisabled {
+		t.Errorf("Expected %v, got %v", errHistoryDisabled, err)
+	}
+	if _, err := server.handleHistoryResource(context.Background(), nil); err != errHistoryDisabled {
+		t.Errorf("Expected %v, got %v", errHistoryDisabled, err)
+	}
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ProgressArgs represents the arguments for the progress tool
+type ProgressArgs struct {
+	ID      string `json:"id" jsonschema:"Identifies the progress notification; reuse it to update the same notification, e.g. build"`
+	Message string `json:"message,omitempty" jsonschema:"The notification message text, e.g. step 3/10: running tests (required unless done is set)"`
+	Title   string `json:"title,omitempty" jsonschema:"The notification title"`
+	Level   string `json:"level,omitempty" jsonschema:"Severity level (defaults to the configured default level)"`
+	Percent *int   `json:"percent,omitempty" jsonschema:"Completion percentage from 0 to 100, shown as a progress bar where supported"`
+	Done    bool   `json:"done,omitempty" jsonschema:"Close the progress notification, e.g. once the task finished"`
+}
+
+// registerProgressToolHandler registers the progress tool with the MCP server
+func (s *Server) registerProgressToolHandler(srv *mcp.Server, workspace string) error {
+	handler := s.handleProgressTool
+	if workspace != "" {
+		handler = func(ctx context.Context, req *mcp.CallToolRequest, args ProgressArgs) (*mcp.CallToolResult, any, error) {
+			return s.progress(ctx, req, args, workspace)
+		}
+	}
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "progress",
+		Description: "Show the progress of a long task as a single notification updated in place, instead of a stack of pokes. Call it with the same id for every step, with an optional percent, and with done=true to close it when the task is over.",
+	}, handler)
+
+	return nil
+}
+
+// handleProgressTool handles the progress tool invocation
+func (s *Server) handleProgressTool(ctx context.Context, req *mcp.CallToolRequest, args ProgressArgs) (*mcp.CallToolResult, any, error) {
+	return s.progress(ctx, req, args, notifier.AppName())
+}
+
+// progress creates, updates or closes the progress notification requested by
+// the progress tool on behalf of workspace
+func (s *Server) progress(ctx context.Context, req *mcp.CallToolRequest, args ProgressArgs, workspace string) (*mcp.CallToolResult, any, error) {
+	cfg, noti := s.state()
+	if err := validateProgressArgs(args); err != nil {
+		if cfg.Notification.Verbose {
+			log.Printf("[MCP Server] Parameter validation error: %v", err)
+		}
+		return nil, nil, err
+	}
+
+	// Workspaces cannot update each other's notifications
+	id := workspace + ":" + args.ID
+
+	if args.Done {
+		if cfg.Notification.Verbose {
+			log.Printf("[MCP Server] Closing progress notification %s", id)
+		}
+		err := notifier.Close(ctx, noti, id)
+		if errors.Is(err, notifier.ErrNotSupported) {
+			return textResult(fmt.Sprintf("Progress %s finished; the notification backend cannot close notifications", args.ID)), nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to close progress notification: %w", err)
+		}
+		return textResult(fmt.Sprintf("Progress %s closed", args.ID)), nil, nil
+	}
+
+	message, title, level, err := validatePokeArgs(PokeArgs{Message: args.Message, Title: args.Title, Level: args.Level}, cfg)
+	if err != nil {
+		if cfg.Notification.Verbose {
+			log.Printf("[MCP Server] Parameter validation error: %v", err)
+		}
+		return nil, nil, err
+	}
+
+	percent := -1
+	if args.Percent != nil {
+		percent = *args.Percent
+	}
+
+	if cfg.Notification.Verbose {
+		log.Printf("[MCP Server] Received progress request - ID: %s, Title: %s, Message: %s, Level: %s, Percent: %d",
+			id, title, message, level, percent)
+	}
+
+	notification := s.renderNotification(cfg, req, title, message, level, workspace)
+	receipt, err := notifier.Update(ctx, noti, id, notification, percent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update progress notification: %w", err)
+	}
+
+	text := fmt.Sprintf("Progress %s updated: %s - %s [%s]", args.ID, notification.Title, notification.Message, level)
+	if percent >= 0 {
+		text += fmt.Sprintf(" (%d%%)", percent)
+	}
+	if receipt.Status != "" {
+		text = fmt.Sprintf("Progress %s %s: %s - %s [%s]", args.ID, receipt.Status, notification.Title, notification.Message, level)
+	}
+	return textResult(text + deliveriesSummary(receipt)), nil, nil
+}
+
+// validateProgressArgs checks the arguments that poke does not share
+func validateProgressArgs(args ProgressArgs) error {
+	if args.ID == "" {
+		return fmt.Errorf("id cannot be empty")
+	}
+	if args.Percent != nil && (*args.Percent < 0 || *args.Percent > 100) {
+		return fmt.Errorf("percent must be between 0 and 100, got %d", *args.Percent)
+	}
+	return nil
+}
+
+// textResult returns a tool result with a single text content
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}
+}
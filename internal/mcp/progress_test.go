@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// updatingNotifier records the notifications it updates in place and closes
+type updatingNotifier struct {
+	recordingNotifier
+	updates map[string]int
+	percent int
+	closed  []string
+}
+
+func (u *updatingNotifier) Update(ctx context.Context, id string, n notifier.Notification, percent int) (notifier.Receipt, error) {
+	if u.updates == nil {
+		u.updates = make(map[string]int)
+	}
+	u.updates[id]++
+	u.percent = percent
+	return u.Send(ctx, n)
+}
+
+func (u *updatingNotifier) Close(ctx context.Context, id string) error {
+	u.closed = append(u.closed, id)
+	return nil
+}
+
+// resultText returns the text of a single text content tool result
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	if result == nil || len(result.Content) != 1 {
+		t.Fatalf("Expected a single content, got %+v", result)
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected text content, got %T", result.Content[0])
+	}
+	return text.Text
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestHandleProgressTool_UpdatesInPlace(t *testing.T) {
+	noti := &updatingNotifier{}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	for step, percent := range []int{10, 60} {
+		result, _, err := server.progress(context.Background(), nil, ProgressArgs{
+			ID: "build", Title: "Build", Message: "compiling", Percent: intPtr(percent),
+		}, "projects/foo")
+		if err != nil {
+			t.Fatalf("Step %d failed: %v", step, err)
+		}
+		if text := resultText(t, result); !strings.Contains(text, "Progress build updated") {
+			t.Errorf("Unexpected result: %s", text)
+		}
+	}
+	if noti.updates["projects/foo:build"] != 2 {
+		t.Errorf("Expected both steps to update the workspace-scoped id, got %v", noti.updates)
+	}
+	if noti.percent != 60 || noti.workspace != "projects/foo" {
+		t.Errorf("Expected the last step at 60%% from projects/foo, got %d%% from %s", noti.percent, noti.workspace)
+	}
+
+	if _, _, err := server.progress(context.Background(), nil, ProgressArgs{ID: "build", Done: true}, "projects/foo"); err != nil {
+		t.Fatalf("Closing failed: %v", err)
+	}
+	if len(noti.closed) != 1 || noti.closed[0] != "projects/foo:build" {
+		t.Errorf("Expected the progress notification to be closed, got %v", noti.closed)
+	}
+}
+
+func TestHandleProgressTool_UnsupportedBackend(t *testing.T) {
+	noti := &recordingNotifier{}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	if _, _, err := server.progress(context.Background(), nil, ProgressArgs{ID: "build", Message: "compiling", Percent: intPtr(30)}, "projects/foo"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if noti.message != "compiling (30%)" {
+		t.Errorf("Expected a new notification with the percentage in the message, got %q", noti.message)
+	}
+
+	result, _, err := server.progress(context.Background(), nil, ProgressArgs{ID: "build", Done: true}, "projects/foo")
+	if err != nil {
+		t.Fatalf("Closing failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "cannot close") {
+		t.Errorf("Expected the result to say the backend cannot close notifications, got %q", text)
+	}
+}
+
+func TestValidateProgressArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  ProgressArgs
+		valid bool
+	}{
+		{"valid", ProgressArgs{ID: "build", Message: "step 1/3", Percent: intPtr(33)}, true},
+		{"done", ProgressArgs{ID: "build", Done: true}, true},
+		{"missing id", ProgressArgs{Message: "step 1/3"}, false},
+		{"percent too high", ProgressArgs{ID: "build", Message: "step 1/3", Percent: intPtr(101)}, false},
+		{"negative percent", ProgressArgs{ID: "build", Message: "step 1/3", Percent: intPtr(-1)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProgressArgs(tt.args)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid arguments, got: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+
+	server := NewServer(config.DefaultConfig(), &recordingNotifier{})
+	if _, _, err := server.progress(context.Background(), nil, ProgressArgs{ID: "build"}, "projects/foo"); err == nil {
+		t.Error("Expected error for a progress update without a message")
+	}
+}
@@ -4,24 +4,46 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
 	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/clobrano/mcp-desktop-notification/internal/render"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Server represents the MCP server for desktop notifications
 type Server struct {
+	// config, notifier and history are replaced together on reload; read them through state
+	stateMu  sync.RWMutex
 	config   *config.Config
 	notifier notifier.Notifier
-	mcp      *mcp.Server
+	history  *history.Store
+
+	// mcp is the default server, created when serving starts; read it through defaultServer
+	mcp *mcp.Server
+
+	// servers holds one MCP server per remote workspace, such as a token name
+	mu      sync.Mutex
+	servers map[string]*mcp.Server
+
+	// issued are the notification ids poke returned, which dismiss accepts
+	issued issuedIDs
 }
 
 // PokeArgs represents the arguments for the poke tool
 type PokeArgs struct {
-	Message string `json:"message" jsonschema:"The notification message text"`
-	Title   string `json:"title,omitempty" jsonschema:"The notification title"`
-	Level   string `json:"level,omitempty" jsonschema:"Severity level: info, warning, error, or success"`
+	Message string   `json:"message" jsonschema:"The notification message text"`
+	Title   string   `json:"title,omitempty" jsonschema:"The notification title"`
+	Level   string   `json:"level,omitempty" jsonschema:"Severity level"`
+	Actions []string `json:"actions,omitempty" jsonschema:"Labels of buttons to show on the notification, e.g. [\"Approve\", \"Deny\"]"`
+	Wait    bool     `json:"wait,omitempty" jsonschema:"Wait for the user to pick one of the actions and return the choice"`
+	Timeout int      `json:"timeout,omitempty" jsonschema:"Seconds to wait for the user's choice when wait is set, at most the configured action timeout (the default)"`
 }
 
 // NewServer creates a new MCP server
@@ -32,46 +54,206 @@ func NewServer(cfg *config.Config, noti notifier.Notifier) *Server {
 	}
 }
 
-// Start initializes and starts the MCP server
+// state returns the configuration and notifier in use. Handlers take them once
+// so a reload in the middle of a request cannot mix two configurations
+func (s *Server) state() (*config.Config, notifier.Notifier) {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.config, s.notifier
+}
+
+// historyStore returns the history store in use, nil when history is disabled
+func (s *Server) historyStore() *history.Store {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.history
+}
+
+// defaultServer returns the MCP server of the local workspace, nil before serving starts
+func (s *Server) defaultServer() *mcp.Server {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.mcp
+}
+
+// verbose reports whether verbose logging is enabled in the current configuration
+func (s *Server) verbose() bool {
+	cfg, _ := s.state()
+	return cfg.Notification.Verbose
+}
+
+// Reload switches to a new configuration, notifier and history store at once,
+// updating the tools of connected clients (e.g. the level enum). It returns the
+// previous notifier, which the caller should flush
+func (s *Server) Reload(cfg *config.Config, noti notifier.Notifier, store *history.Store) (notifier.Notifier, error) {
+	s.stateMu.Lock()
+	previous := s.notifier
+	s.config, s.notifier, s.history = cfg, noti, store
+	srv := s.mcp
+	s.stateMu.Unlock()
+
+	// Servers are created when serving starts
+	if srv == nil {
+		return previous, nil
+	}
+
+	if err := s.registerTools(srv, ""); err != nil {
+		return previous, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for workspace, workspaceSrv := range s.servers {
+		if err := s.registerTools(workspaceSrv, workspace); err != nil {
+			return previous, err
+		}
+	}
+	return previous, nil
+}
+
+// Start initializes and starts the MCP server on stdio
 func (s *Server) Start() error {
+	return s.Serve(TransportStdio, "")
+}
+
+// init creates the MCP server and registers the tools and resources
+func (s *Server) init() error {
+	srv, err := s.newMCPServer("")
+	if err != nil {
+		return err
+	}
+	s.stateMu.Lock()
+	s.mcp = srv
+	s.stateMu.Unlock()
+	return nil
+}
+
+// newMCPServer creates an MCP server with the tools and resources registered.
+// A non-empty workspace replaces the local one in the notifications it sends
+func (s *Server) newMCPServer(workspace string) (*mcp.Server, error) {
 	// Create MCP server
-	mcpServer := mcp.NewServer(&mcp.Implementation{
+	srv := mcp.NewServer(&mcp.Implementation{
 		Name:    "mcp-poke",
 		Version: "1.0.0",
 	}, nil)
 
-	s.mcp = mcpServer
+	if err := s.registerTools(srv, workspace); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
 
+// registerTools registers the tools and resources for the current
+// configuration on srv, replacing those registered before
+func (s *Server) registerTools(srv *mcp.Server, workspace string) error {
 	// Register the poke tool
-	s.registerPokeToolHandler()
+	if err := s.registerPokeToolHandler(srv, workspace); err != nil {
+		return err
+	}
 
-	if s.config.Notification.Verbose {
-		log.Println("[MCP Server] Starting MCP server on stdio")
+	// Register the progress tool
+	if err := s.registerProgressToolHandler(srv, workspace); err != nil {
+		return err
 	}
 
-	// Start the server (blocking)
-	if err := mcpServer.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		return fmt.Errorf("server error: %w", err)
+	// Register the dismiss tool
+	s.registerDismissToolHandler(srv, workspace)
+
+	// Register history resource and tool when a store is configured
+	if s.historyStore() != nil {
+		s.registerHistoryHandlers(srv)
+	} else {
+		srv.RemoveTools("list_notifications")
+		srv.RemoveResources(historyResourceURI)
 	}
 
 	return nil
 }
 
+// serverFor returns the MCP server sending notifications on behalf of
+// workspace, creating it on first use. Empty workspace uses the default server
+func (s *Server) serverFor(workspace string) *mcp.Server {
+	if workspace == "" {
+		return s.defaultServer()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if srv, ok := s.servers[workspace]; ok {
+		return srv
+	}
+	srv, err := s.newMCPServer(workspace)
+	if err != nil {
+		// The default server was built from the same configuration, so this is not expected
+		return s.defaultServer()
+	}
+	if s.servers == nil {
+		s.servers = make(map[string]*mcp.Server)
+	}
+	s.servers[workspace] = srv
+	return srv
+}
+
 // registerPokeToolHandler registers the poke tool with the MCP server
-func (s *Server) registerPokeToolHandler() {
+func (s *Server) registerPokeToolHandler(srv *mcp.Server, workspace string) error {
+	cfg, _ := s.state()
+	inputSchema, err := pokeInputSchema(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build poke tool schema: %w", err)
+	}
+
+	handler := s.handlePokeTool
+	if workspace != "" {
+		handler = func(ctx context.Context, req *mcp.CallToolRequest, args PokeArgs) (*mcp.CallToolResult, any, error) {
+			return s.poke(ctx, req, args, workspace)
+		}
+	}
+
 	// Define the poke tool using AddTool
-	mcp.AddTool(s.mcp, &mcp.Tool{
+	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "poke",
-		Description: "Send a desktop notification to alert the user. ALWAYS notify before requesting user input or approval. Also use to report completions, errors, warnings, and updates when the user may be in another application.",
-	}, s.handlePokeTool)
+		Description: "Send a desktop notification to alert the user. ALWAYS notify before requesting user input or approval. Also use to report completions, errors, warnings, and updates when the user may be in another application. To ask for approval, pass actions (e.g. [\"Approve\", \"Deny\"]) with wait=true and the user's choice is returned.",
+		InputSchema: inputSchema,
+	}, handler)
+
+	return nil
+}
+
+// pokeInputSchema returns the poke tool input schema, with the level enum
+// generated from the configured levels so agents see the real set
+func pokeInputSchema(cfg *config.Config) (*jsonschema.Schema, error) {
+	schema, err := jsonschema.For[PokeArgs](nil)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := cfg.LevelNames()
+	enum := make([]any, len(levels))
+	for i, level := range levels {
+		enum[i] = level
+	}
+
+	levelSchema := schema.Properties["level"]
+	levelSchema.Enum = enum
+	levelSchema.Description = fmt.Sprintf("Severity level: one of %s (defaults to %s)",
+		strings.Join(levels, ", "), cfg.Notification.DefaultLevel)
+
+	return schema, nil
 }
 
 // handlePokeTool handles the poke tool invocation
 func (s *Server) handlePokeTool(ctx context.Context, req *mcp.CallToolRequest, args PokeArgs) (*mcp.CallToolResult, any, error) {
+	return s.poke(ctx, req, args, notifier.AppName())
+}
+
+// poke sends the notification requested by the poke tool on behalf of workspace
+func (s *Server) poke(ctx context.Context, req *mcp.CallToolRequest, args PokeArgs, workspace string) (*mcp.CallToolResult, any, error) {
+	cfg, noti := s.state()
+
 	// Validate and extract parameters
-	message, title, level, err := validatePokeArgs(args)
+	message, title, level, err := validatePokeArgs(args, cfg)
 	if err != nil {
-		if s.config.Notification.Verbose {
+		if cfg.Notification.Verbose {
 			log.Printf("[MCP Server] Parameter validation error: %v", err)
 		}
 		// Return error
@@ -79,25 +261,67 @@ func (s *Server) handlePokeTool(ctx context.Context, req *mcp.CallToolRequest, a
 	}
 
 	// Log the request if verbose
-	if s.config.Notification.Verbose {
+	if cfg.Notification.Verbose {
 		log.Printf("[MCP Server] Received poke request - Title: %s, Message: %s, Level: %s", title, message, level)
 	}
 
+	// Render title and body through the configured templates
+	notification := s.renderNotification(cfg, req, title, message, level, workspace)
+	title, message = notification.Title, notification.Message
+	notification.Actions = args.Actions
+
+	// The notifier stops listening for actions once sendCtx is done, which
+	// happens right after sending unless the caller waits for the choice
+	timeout := actionTimeout(cfg, args)
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	// Send notification
-	if err := s.notifier.Send(title, message, level); err != nil {
+	receipt, err := noti.Send(sendCtx, notification)
+	if err != nil {
 		errMsg := fmt.Sprintf("Failed to send notification: %v", err)
-		if s.config.Notification.Verbose {
+		if cfg.Notification.Verbose {
 			log.Printf("[MCP Server] %s", errMsg)
 		}
 		return nil, nil, fmt.Errorf("%s", errMsg)
 	}
 
+	// Report notifications that were deliberately not shown right away
+	if receipt.Status != "" {
+		if cfg.Notification.Verbose {
+			log.Printf("[MCP Server] Notification %s", receipt.Status)
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Notification %s: %s - %s [%s]", receipt.Status, title, message, level) +
+					deliveriesSummary(receipt)},
+			},
+		}, nil, nil
+	}
+
 	// Return success
-	if s.config.Notification.Verbose {
+	if cfg.Notification.Verbose {
 		log.Printf("[MCP Server] Notification sent successfully")
 	}
 
 	successMsg := fmt.Sprintf("Notification sent: %s - %s [%s]", title, message, level)
+	if receipt.ID != "" {
+		s.issued.add(workspace, receipt.ID)
+		successMsg += fmt.Sprintf(" (id %s, pass it to dismiss to close the notification)", receipt.ID)
+	}
+	successMsg += deliveriesSummary(receipt)
+
+	if args.Wait {
+		outcome, err := awaitResponse(ctx, sendCtx, receipt, timeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.Notification.Verbose {
+			log.Printf("[MCP Server] %s", outcome)
+		}
+		successMsg += "\n" + outcome
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			&mcp.TextContent{Text: successMsg},
@@ -105,8 +329,94 @@ func (s *Server) handlePokeTool(ctx context.Context, req *mcp.CallToolRequest, a
 	}, nil, nil
 }
 
+// renderNotification renders title and message through the configured templates
+func (s *Server) renderNotification(cfg *config.Config, req *mcp.CallToolRequest, title, message, level, workspace string) notifier.Notification {
+	client := clientInfo(req)
+	title, message, err := render.Render(cfg.TemplateFor(level), render.Data{
+		Title:     title,
+		Message:   message,
+		Level:     level,
+		Timestamp: time.Now(),
+		Workspace: workspace,
+		Client:    client,
+	})
+	if err != nil && cfg.Notification.Verbose {
+		log.Printf("[MCP Server] Template error, using raw title/message: %v", err)
+	}
+
+	return notifier.Notification{
+		Title:     title,
+		Message:   message,
+		Level:     level,
+		Workspace: workspace,
+		Client:    client.Name,
+	}
+}
+
+// deliveriesSummary lists the outcome of each backend, when several are configured
+func deliveriesSummary(receipt notifier.Receipt) string {
+	if len(receipt.Deliveries) == 0 {
+		return ""
+	}
+	outcomes := make([]string, len(receipt.Deliveries))
+	for i, delivery := range receipt.Deliveries {
+		outcomes[i] = delivery.String()
+	}
+	return "\nBackends: " + strings.Join(outcomes, ", ")
+}
+
+// actionTimeout returns how long to wait for the user's choice
+func actionTimeout(cfg *config.Config, args PokeArgs) time.Duration {
+	if args.Timeout > 0 {
+		return time.Duration(args.Timeout) * time.Second
+	}
+	return time.Duration(cfg.Notification.ActionTimeout) * time.Second
+}
+
+// awaitResponse waits for the user's reaction to a notification with actions.
+// It returns an error only if the request itself is cancelled
+func awaitResponse(ctx, waitCtx context.Context, receipt notifier.Receipt, timeout time.Duration) (string, error) {
+	if receipt.Response == nil {
+		return "The notification backend cannot report actions; ask the user directly", nil
+	}
+
+	var response notifier.Response
+	var ok bool
+	select {
+	case response, ok = <-receipt.Response:
+	case <-waitCtx.Done():
+	}
+
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("stopped waiting for user response: %w", err)
+		}
+		return fmt.Sprintf("No action selected within %s", timeout), nil
+	}
+
+	if response.Action == "" {
+		return fmt.Sprintf("Notification closed without selecting an action (%s)", response.CloseReason), nil
+	}
+	return fmt.Sprintf("User selected action: %s", response.Action), nil
+}
+
+// clientInfo returns the name and version the MCP client reported at initialization
+func clientInfo(req *mcp.CallToolRequest) render.Client {
+	if req == nil || req.Session == nil {
+		return render.Client{}
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return render.Client{}
+	}
+	return render.Client{
+		Name:    params.ClientInfo.Name,
+		Version: params.ClientInfo.Version,
+	}
+}
+
 // validatePokeArgs validates and extracts parameters from PokeArgs
-func validatePokeArgs(args PokeArgs) (message, title, level string, err error) {
+func validatePokeArgs(args PokeArgs, cfg *config.Config) (message, title, level string, err error) {
 	// Validate message (required)
 	if args.Message == "" {
 		return "", "", "", fmt.Errorf("message cannot be empty")
@@ -119,28 +429,49 @@ func validatePokeArgs(args PokeArgs) (message, title, level string, err error) {
 		title = "Notification"
 	}
 
-	// Set level (optional, default to "info")
+	// Set level (optional, default to the configured default level)
 	level = args.Level
 	if level == "" {
-		level = "info"
+		level = cfg.Notification.DefaultLevel
+	}
+
+	if err := validateLevel(level, cfg); err != nil {
+		return "", "", "", err
 	}
 
-	// Validate level
-	validLevels := map[string]bool{
-		"info":    true,
-		"warning": true,
-		"error":   true,
-		"success": true,
+	// Validate actions (optional)
+	for i, action := range args.Actions {
+		if action == "" {
+			return "", "", "", fmt.Errorf("action labels cannot be empty")
+		}
+		if slices.Contains(args.Actions[:i], action) {
+			return "", "", "", fmt.Errorf("duplicate action label: %s", action)
+		}
+	}
+	if args.Wait && len(args.Actions) == 0 {
+		return "", "", "", fmt.Errorf("wait requires at least one action")
 	}
-	if !validLevels[level] {
-		return "", "", "", fmt.Errorf("invalid level: %s (must be one of: info, warning, error, success)", level)
+	if args.Timeout < 0 {
+		return "", "", "", fmt.Errorf("timeout cannot be negative")
+	}
+	// The configured timeout bounds how long a tool call can be held open
+	if args.Timeout > cfg.Notification.ActionTimeout {
+		return "", "", "", fmt.Errorf("timeout cannot exceed the configured action timeout of %d seconds", cfg.Notification.ActionTimeout)
 	}
 
 	return message, title, level, nil
 }
 
+// validateLevel checks that level is one of the configured levels
+func validateLevel(level string, cfg *config.Config) error {
+	if _, ok := cfg.Notification.Levels[level]; !ok {
+		return fmt.Errorf("invalid level: %s (must be one of: %s)", level, strings.Join(cfg.LevelNames(), ", "))
+	}
+	return nil
+}
+
 // validatePokeParams validates and extracts parameters from the poke tool call (for backward compatibility with tests)
-func validatePokeParams(params map[string]interface{}) (message, title, level string, err error) {
+func validatePokeParams(params map[string]interface{}, cfg *config.Config) (message, title, level string, err error) {
 	// Extract message (required)
 	msgVal, ok := params["message"]
 	if !ok {
@@ -164,19 +495,12 @@ func validatePokeParams(params map[string]interface{}) (message, title, level st
 		}
 	}
 
-	// Extract level (optional, default to "info")
-	level = "info"
+	// Extract level (optional, default to the configured default level)
+	level = cfg.Notification.DefaultLevel
 	if levelVal, ok := params["level"]; ok {
 		if levelStr, ok := levelVal.(string); ok {
-			// Validate level
-			validLevels := map[string]bool{
-				"info":    true,
-				"warning": true,
-				"error":   true,
-				"success": true,
-			}
-			if !validLevels[levelStr] {
-				return "", "", "", fmt.Errorf("invalid level: %s (must be one of: info, warning, error, success)", levelStr)
+			if err := validateLevel(levelStr, cfg); err != nil {
+				return "", "", "", err
 			}
 			level = levelStr
 		}
@@ -1,10 +1,16 @@
 package mcp
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/clobrano/mcp-desktop-notification/internal/config"
 	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestNewServer(t *testing.T) {
@@ -19,15 +25,64 @@ func TestNewServer(t *testing.T) {
 		t.Error("Expected non-nil server")
 	}
 
-	if server.config != cfg {
+	serverConfig, serverNotifier := server.state()
+	if serverConfig != cfg {
 		t.Error("Server config not set correctly")
 	}
 
-	if server.notifier != noti {
+	if serverNotifier != noti {
 		t.Error("Server notifier not set correctly")
 	}
 }
 
+func TestServer_Reload(t *testing.T) {
+	previous := &recordingNotifier{}
+	server := NewServer(config.DefaultConfig(), previous)
+	session := connectTestClient(t, server)
+	ctx := context.Background()
+
+	cfg := config.DefaultConfig()
+	cfg.Notification.Levels["deploy"] = config.Level{Urgency: "normal"}
+	noti := &recordingNotifier{}
+	old, err := server.Reload(cfg, noti, nil)
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if old != previous {
+		t.Error("Expected Reload to return the previous notifier")
+	}
+
+	// Connected clients see the new level in the schema and can use it
+	tools, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to list tools: %v", err)
+	}
+	var schema string
+	for _, tool := range tools.Tools {
+		if tool.Name == "poke" {
+			encoded, _ := json.Marshal(tool.InputSchema)
+			schema = string(encoded)
+		}
+	}
+	if !strings.Contains(schema, `"deploy"`) {
+		t.Errorf("Expected the deploy level in the poke schema, got %s", schema)
+	}
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "poke",
+		Arguments: map[string]any{"message": "Released", "level": "deploy"},
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("poke failed: %v %+v", err, result)
+	}
+	if noti.message != "Released" {
+		t.Errorf("Expected the new notifier to send, got %q", noti.message)
+	}
+	if previous.message != "" {
+		t.Errorf("Expected the previous notifier to be unused, got %q", previous.message)
+	}
+}
+
 func TestValidatePokeParams_Valid(t *testing.T) {
 	params := map[string]interface{}{
 		"message": "Test message",
@@ -35,7 +90,7 @@ func TestValidatePokeParams_Valid(t *testing.T) {
 		"level":   "info",
 	}
 
-	message, title, level, err := validatePokeParams(params)
+	message, title, level, err := validatePokeParams(params, config.DefaultConfig())
 	if err != nil {
 		t.Errorf("Expected valid params, got error: %v", err)
 	}
@@ -58,7 +113,7 @@ func TestValidatePokeParams_MissingMessage(t *testing.T) {
 		"title": "Test title",
 	}
 
-	_, _, _, err := validatePokeParams(params)
+	_, _, _, err := validatePokeParams(params, config.DefaultConfig())
 	if err == nil {
 		t.Error("Expected error for missing message parameter")
 	}
@@ -69,7 +124,7 @@ func TestValidatePokeParams_DefaultTitle(t *testing.T) {
 		"message": "Test message",
 	}
 
-	message, title, level, err := validatePokeParams(params)
+	message, title, level, err := validatePokeParams(params, config.DefaultConfig())
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -93,7 +148,7 @@ func TestValidatePokeParams_InvalidLevel(t *testing.T) {
 		"level":   "invalid",
 	}
 
-	_, _, _, err := validatePokeParams(params)
+	_, _, _, err := validatePokeParams(params, config.DefaultConfig())
 	if err == nil {
 		t.Error("Expected error for invalid level")
 	}
@@ -104,7 +159,7 @@ func TestValidatePokeParams_EmptyMessage(t *testing.T) {
 		"message": "",
 	}
 
-	_, _, _, err := validatePokeParams(params)
+	_, _, _, err := validatePokeParams(params, config.DefaultConfig())
 	if err == nil {
 		t.Error("Expected error for empty message")
 	}
@@ -115,8 +170,291 @@ func TestValidatePokeParams_WrongType(t *testing.T) {
 		"message": 123, // Should be string
 	}
 
-	_, _, _, err := validatePokeParams(params)
+	_, _, _, err := validatePokeParams(params, config.DefaultConfig())
 	if err == nil {
 		t.Error("Expected error for wrong parameter type")
 	}
 }
+
+// recordingNotifier captures the notifications it is asked to send
+type recordingNotifier struct {
+	title, message, level string
+	workspace             string
+	actions               []string
+	receipt               notifier.Receipt
+}
+
+func (r *recordingNotifier) Send(ctx context.Context, n notifier.Notification) (notifier.Receipt, error) {
+	r.title, r.message, r.level, r.actions = n.Title, n.Message, n.Level, n.Actions
+	r.workspace = n.Workspace
+	return r.receipt, nil
+}
+
+func TestHandlePokeTool_RendersTemplates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Template.Title = "{{.Level}}: {{.Title}}"
+	cfg.Notification.Template.Body = "{{.Message}} ({{.Workspace}})"
+	noti := &recordingNotifier{}
+	server := NewServer(cfg, noti)
+
+	_, _, err := server.handlePokeTool(context.Background(), nil, PokeArgs{
+		Message: "Build finished",
+		Title:   "CI",
+		Level:   "success",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if noti.title != "success: CI" {
+		t.Errorf("Expected rendered title 'success: CI', got %q", noti.title)
+	}
+	expected := "Build finished (" + notifier.AppName() + ")"
+	if noti.message != expected {
+		t.Errorf("Expected rendered body %q, got %q", expected, noti.message)
+	}
+	if noti.level != "success" {
+		t.Errorf("Expected level 'success', got %q", noti.level)
+	}
+}
+
+func TestHandlePokeTool_TemplateErrorFallsBack(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Template.Body = "{{.Missing}}"
+	noti := &recordingNotifier{}
+	server := NewServer(cfg, noti)
+
+	_, _, err := server.handlePokeTool(context.Background(), nil, PokeArgs{Message: "Raw message"})
+	if err != nil {
+		t.Fatalf("Template errors should not fail the tool call, got: %v", err)
+	}
+
+	if noti.message != "Raw message" {
+		t.Errorf("Expected fallback to raw message, got %q", noti.message)
+	}
+}
+
+func TestValidatePokeArgs_CustomLevels(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Levels["approval"] = config.Level{Urgency: "critical"}
+	cfg.Notification.DefaultLevel = "approval"
+
+	_, _, level, err := validatePokeArgs(PokeArgs{Message: "Deploy?", Level: "approval"}, cfg)
+	if err != nil {
+		t.Fatalf("Expected custom level to be valid, got error: %v", err)
+	}
+	if level != "approval" {
+		t.Errorf("Expected level 'approval', got %q", level)
+	}
+
+	_, _, level, err = validatePokeArgs(PokeArgs{Message: "Deploy?"}, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if level != "approval" {
+		t.Errorf("Expected configured default level 'approval', got %q", level)
+	}
+
+	delete(cfg.Notification.Levels, "warning")
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Careful", Level: "warning"}, cfg); err == nil {
+		t.Error("Expected error for level not present in config")
+	}
+}
+
+func TestPokeInputSchema_LevelEnum(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Levels["approval"] = config.Level{Urgency: "critical"}
+	schema, err := pokeInputSchema(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+
+	levelSchema, ok := schema.Properties["level"]
+	if !ok {
+		t.Fatal("Expected level property in schema")
+	}
+
+	expected := []string{"approval", "error", "info", "success", "warning"}
+	if len(levelSchema.Enum) != len(expected) {
+		t.Fatalf("Expected enum %v, got %v", expected, levelSchema.Enum)
+	}
+	for i, level := range expected {
+		if levelSchema.Enum[i] != level {
+			t.Errorf("Expected enum %v, got %v", expected, levelSchema.Enum)
+			break
+		}
+	}
+
+	if _, ok := schema.Properties["message"]; !ok {
+		t.Error("Expected message property in schema")
+	}
+}
+
+func TestHandlePokeTool_PerLevelTemplate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Levels["approval"] = config.Level{
+		Urgency:  "critical",
+		Template: config.Template{Title: "Approval needed: {{.Title}}"},
+	}
+	noti := &recordingNotifier{}
+	server := NewServer(cfg, noti)
+
+	_, _, err := server.handlePokeTool(context.Background(), nil, PokeArgs{
+		Message: "Run migration?",
+		Title:   "Database",
+		Level:   "approval",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if noti.title != "Approval needed: Database" {
+		t.Errorf("Expected per-level title, got %q", noti.title)
+	}
+	if noti.message != "Run migration?" {
+		t.Errorf("Expected global body template, got %q", noti.message)
+	}
+}
+
+// respondWith returns a receipt whose response channel yields response
+func respondWith(response notifier.Response) notifier.Receipt {
+	responses := make(chan notifier.Response, 1)
+	responses <- response
+	close(responses)
+	return notifier.Receipt{Response: responses}
+}
+
+func callPoke(t *testing.T, server *Server, args PokeArgs) string {
+	t.Helper()
+
+	result, _, err := server.handlePokeTool(context.Background(), nil, args)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Content) != 1 {
+		t.Fatalf("Expected one content item, got %d", len(result.Content))
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected text content, got %T", result.Content[0])
+	}
+	return text.Text
+}
+
+func TestHandlePokeTool_WaitForAction(t *testing.T) {
+	noti := &recordingNotifier{receipt: respondWith(notifier.Response{Action: "Approve"})}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	text := callPoke(t, server, PokeArgs{
+		Message: "Deploy to production?",
+		Actions: []string{"Approve", "Deny"},
+		Wait:    true,
+	})
+
+	if !strings.Contains(text, "User selected action: Approve") {
+		t.Errorf("Expected selected action in result, got %q", text)
+	}
+	if strings.Join(noti.actions, ",") != "Approve,Deny" {
+		t.Errorf("Expected actions to be passed to the notifier, got %v", noti.actions)
+	}
+}
+
+func TestHandlePokeTool_WaitClosedWithoutAction(t *testing.T) {
+	noti := &recordingNotifier{receipt: respondWith(notifier.Response{CloseReason: "dismissed"})}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	text := callPoke(t, server, PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true})
+	if !strings.Contains(text, "closed without selecting an action (dismissed)") {
+		t.Errorf("Expected dismissal in result, got %q", text)
+	}
+}
+
+func TestHandlePokeTool_WaitTimeout(t *testing.T) {
+	// The notifier closes the response channel when it stops listening
+	responses := make(chan notifier.Response)
+	close(responses)
+	noti := &recordingNotifier{receipt: notifier.Receipt{Response: responses}}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	text := callPoke(t, server, PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true, Timeout: 5})
+	if !strings.Contains(text, "No action selected within 5s") {
+		t.Errorf("Expected timeout in result, got %q", text)
+	}
+}
+
+func TestHandlePokeTool_WaitUnsupportedBackend(t *testing.T) {
+	server := NewServer(config.DefaultConfig(), &recordingNotifier{})
+
+	text := callPoke(t, server, PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true})
+	if !strings.Contains(text, "cannot report actions") {
+		t.Errorf("Expected unsupported backend note in result, got %q", text)
+	}
+}
+
+func TestHandlePokeTool_WaitCancelled(t *testing.T) {
+	noti := &recordingNotifier{receipt: notifier.Receipt{Response: make(chan notifier.Response)}}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := server.handlePokeTool(ctx, nil, PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true})
+	if err == nil {
+		t.Error("Expected error when the request is cancelled while waiting")
+	}
+}
+
+func TestValidatePokeArgs_Actions(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Wait: true}, cfg); err == nil {
+		t.Error("Expected error for wait without actions")
+	}
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Actions: []string{"Yes", ""}}, cfg); err == nil {
+		t.Error("Expected error for empty action label")
+	}
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Actions: []string{"Yes", "No", "Yes"}}, cfg); err == nil {
+		t.Error("Expected error for duplicate action labels")
+	}
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true, Timeout: -1}, cfg); err == nil {
+		t.Error("Expected error for negative timeout")
+	}
+	// A huge timeout would hold the call open and overflow the duration
+	for _, timeout := range []int{cfg.Notification.ActionTimeout + 1, math.MaxInt} {
+		if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true, Timeout: timeout}, cfg); err == nil {
+			t.Errorf("Expected error for timeout %d over the configured action timeout", timeout)
+		}
+	}
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true, Timeout: cfg.Notification.ActionTimeout}, cfg); err != nil {
+		t.Errorf("Expected the configured action timeout to be accepted, got error: %v", err)
+	}
+	if _, _, _, err := validatePokeArgs(PokeArgs{Message: "Continue?", Actions: []string{"Yes"}, Wait: true}, cfg); err != nil {
+		t.Errorf("Expected valid actions, got error: %v", err)
+	}
+}
+
+func TestHandlePokeTool_ReportsSuppressed(t *testing.T) {
+	noti := &recordingNotifier{receipt: notifier.Receipt{Status: notifier.StatusDuplicate}}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	text := callPoke(t, server, PokeArgs{Message: "Build passed", Title: "CI"})
+	if !strings.Contains(text, "suppressed (duplicate)") {
+		t.Errorf("Expected suppression to be reported, got %q", text)
+	}
+	if strings.Contains(text, "Notification sent") {
+		t.Errorf("Suppressed notification must not be reported as sent, got %q", text)
+	}
+}
+
+func TestHandlePokeTool_ReportsDeliveries(t *testing.T) {
+	noti := &recordingNotifier{receipt: notifier.Receipt{Deliveries: []notifier.Delivery{
+		{Backend: "desktop"},
+		{Backend: "webhook", Err: errors.New("timeout")},
+	}}}
+	server := NewServer(config.DefaultConfig(), noti)
+
+	text := callPoke(t, server, PokeArgs{Message: "Build passed", Title: "CI"})
+	if !strings.Contains(text, "Backends: desktop: sent, webhook: failed (timeout)") {
+		t.Errorf("Expected per-backend outcomes, got %q", text)
+	}
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// socketHello is the first line a client writes on the daemon socket, before
+// the MCP messages, telling the daemon which workspace it notifies for
+type socketHello struct {
+	Workspace string `json:"workspace"`
+}
+
+// listenUnix listens on the socket at path, replacing a stale socket left by a
+// daemon that did not exit cleanly. Only the user running the daemon may
+// connect to it, so nobody else can send notifications through it
+func listenUnix(path string) (net.Listener, error) {
+	if err := checkSocketDir(path); err != nil {
+		return nil, err
+	}
+	if err := checkSocketOwner(path); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := listenPrivate(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// checkSocketDir creates the directory of the socket at path if needed, and
+// makes sure other users cannot reach or replace the socket in it
+func checkSocketDir(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	owner, ok := fileOwner(info)
+	if !ok {
+		return nil
+	}
+
+	// The socket is created with the default umask and then restricted, so
+	// only a private directory keeps others from connecting in between
+	switch mode := info.Mode(); {
+	case !mode.IsDir():
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	case owner != os.Getuid():
+		return fmt.Errorf("socket directory %s is owned by another user", dir)
+	case mode.Perm()&0077 != 0:
+		return fmt.Errorf("socket directory %s is accessible to other users (mode %o)", dir, mode.Perm())
+	}
+	return nil
+}
+
+// checkSocketOwner makes sure the socket at path, if there is one, belongs to
+// the current user, so it is not talking to or removing someone else's
+func checkSocketOwner(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check socket: %w", err)
+	}
+	if owner, ok := fileOwner(info); ok && owner != os.Getuid() {
+		return fmt.Errorf("socket %s is owned by another user", path)
+	}
+	return nil
+}
+
+// serveSocket serves one MCP session per connection on listener until ctx is done
+func (s *Server) serveSocket(ctx context.Context, listener net.Listener) error {
+	if s.verbose() {
+		log.Printf("[MCP Server] Starting MCP daemon on %s", listener.Addr())
+	}
+
+	var wg sync.WaitGroup
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	var err error
+	for {
+		var conn net.Conn
+		conn, err = listener.Accept()
+		if err != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// serveConn reads the client's hello and serves its MCP session
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	var hello socketHello
+	if err == nil {
+		err = json.Unmarshal(line, &hello)
+	}
+	if err != nil {
+		if s.verbose() {
+			log.Printf("[MCP Server] Dropping connection without a valid hello: %v", err)
+		}
+		return
+	}
+
+	if s.verbose() {
+		log.Printf("[MCP Server] Client connected for workspace %s", hello.Workspace)
+	}
+
+	session, err := s.serverFor(hello.Workspace).Connect(ctx, &mcp.IOTransport{
+		Reader: readCloser{reader, conn},
+		Writer: conn,
+	}, nil)
+	if err != nil {
+		log.Printf("[MCP Server] Failed to start session: %v", err)
+		return
+	}
+	session.Wait()
+}
+
+// readCloser reads through a buffered reader and closes the underlying connection
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Proxy connects to the daemon socket at path and relays MCP messages between
+// it and in/out, so MCP clients can launch it like a stdio server. It returns
+// once the daemon closes the connection
+func Proxy(path string, in io.Reader, out io.Writer) error {
+	if err := checkSocketOwner(path); err != nil {
+		return err
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon (is mcp-poke -transport=unix running?): %w", err)
+	}
+	defer conn.Close()
+
+	hello, err := json.Marshal(socketHello{Workspace: notifier.AppName()})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(append(hello, '\n')); err != nil {
+		return fmt.Errorf("failed to greet daemon: %w", err)
+	}
+
+	// Once the client is done writing, let the daemon finish answering before returning
+	go func() {
+		io.Copy(conn, in)
+		if unixConn, ok := conn.(*net.UnixConn); ok {
+			unixConn.CloseWrite()
+		}
+	}()
+
+	if _, err := io.Copy(out, conn); err != nil {
+		return fmt.Errorf("connection to daemon failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// startTestDaemon serves server on a socket in a temporary directory and returns its path
+func startTestDaemon(t *testing.T, server *Server) string {
+	t.Helper()
+
+	// Socket paths are limited to about 100 bytes, so avoid the long t.TempDir()
+	dir, err := os.MkdirTemp("", "poke")
+	if err != nil {
+		t.Fatalf("Failed to create socket directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "mcp-poke.sock")
+
+	if err := server.init(); err != nil {
+		t.Fatalf("Failed to initialize server: %v", err)
+	}
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.serveSocket(ctx, listener)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return path
+}
+
+// connectThroughProxy connects an MCP client to the daemon through Proxy
+func connectThroughProxy(t *testing.T, path, workspace string) *mcp.ClientSession {
+	t.Helper()
+
+	t.Setenv("PWD", workspace)
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	go func() {
+		Proxy(path, inReader, outWriter)
+		outWriter.Close()
+	}()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "local-agent", Version: "0.1.0"}, nil)
+	session, err := client.Connect(context.Background(), &mcp.IOTransport{Reader: outReader, Writer: inWriter}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect through proxy: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestSocketDaemon_SharedAcrossClients(t *testing.T) {
+	noti := &recordingNotifier{}
+	path := startTestDaemon(t, NewServer(config.DefaultConfig(), noti))
+
+	for _, workspace := range []string{"/home/user/projects/foo", "/home/user/projects/bar"} {
+		session := connectThroughProxy(t, path, workspace)
+		if _, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "poke",
+			Arguments: map[string]any{"message": "Done"},
+		}); err != nil {
+			t.Fatalf("poke failed: %v", err)
+		}
+
+		expected := filepath.Join("projects", filepath.Base(workspace))
+		if noti.workspace != expected {
+			t.Errorf("Expected workspace %q from the proxy, got %q", expected, noti.workspace)
+		}
+	}
+}
+
+func TestListenUnix_AlreadyRunning(t *testing.T) {
+	path := startTestDaemon(t, NewServer(config.DefaultConfig(), &recordingNotifier{}))
+	if _, err := listenUnix(path); err == nil {
+		t.Error("Expected error when another daemon is listening")
+	}
+}
+
+func TestListenUnix_StaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "poke")
+	if err != nil {
+		t.Fatalf("Failed to create socket directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mcp-poke.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got: %v", err)
+	}
+	listener.Close()
+}
+
+func TestProxy_NoDaemon(t *testing.T) {
+	if err := Proxy(filepath.Join(t.TempDir(), "missing.sock"), nil, io.Discard); err == nil {
+		t.Error("Expected error when no daemon is listening")
+	}
+}
+
+// newSocketDir returns a short private directory for a socket
+func newSocketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "poke")
+	if err != nil {
+		t.Fatalf("Failed to create socket directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestListenUnix_Permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Socket permissions are not used on Windows")
+	}
+
+	// The directory is created private
+	dir := filepath.Join(newSocketDir(t), "mcp-poke-1000")
+	path := filepath.Join(dir, "mcp-poke.sock")
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	for file, expected := range map[string]os.FileMode{dir: 0700, path: 0600} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", file, err)
+		}
+		if perm := info.Mode().Perm(); perm != expected {
+			t.Errorf("Expected %s to have mode %o, got %o", file, expected, perm)
+		}
+	}
+}
+
+func TestListenUnix_SharedDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Socket permissions are not used on Windows")
+	}
+
+	// Others could connect to or replace the socket in a directory they can enter
+	for _, mode := range []os.FileMode{0777, 0755, 0777 | os.ModeSticky} {
+		dir := newSocketDir(t)
+		if err := os.Chmod(dir, mode); err != nil {
+			t.Fatalf("Failed to change mode: %v", err)
+		}
+		if _, err := listenUnix(filepath.Join(dir, "mcp-poke.sock")); err == nil {
+			t.Errorf("Expected error for a directory with mode %o", mode)
+		}
+	}
+}
+
+func TestListenUnix_OtherUsersSocket(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("Changing the owner of the socket needs root")
+	}
+
+	path := filepath.Join(newSocketDir(t), "mcp-poke.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	if err := os.Chown(path, 65534, 65534); err != nil {
+		t.Fatalf("Failed to change owner: %v", err)
+	}
+
+	if _, err := listenUnix(path); err == nil {
+		t.Error("Expected error for a socket owned by another user")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the other user's socket to be left alone: %v", err)
+	}
+	if err := Proxy(path, nil, io.Discard); err == nil {
+		t.Error("Expected the proxy not to connect to a socket owned by another user")
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package mcp
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the user id owning the file
+func fileOwner(info os.FileInfo) (int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Uid), true
+}
+
+// listenPrivate listens on the socket at path, in a directory only the
+// current user can enter, and restricts the socket to its owner
+func listenPrivate(path string) (net.Listener, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
@@ -0,0 +1,16 @@
+package mcp
+
+import (
+	"net"
+	"os"
+)
+
+// fileOwner reports no owner: Windows restricts access with ACLs instead
+func fileOwner(info os.FileInfo) (int, bool) {
+	return 0, false
+}
+
+// listenPrivate listens on the socket at path
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Transports the MCP server can be reached over
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+	TransportSSE   = "sse"
+	TransportUnix  = "unix"
+)
+
+// defaultHTTPAddr is where the http and sse transports listen unless told otherwise
+const defaultHTTPAddr = "localhost:8787"
+
+// shutdownTimeout bounds how long the HTTP listener waits for open requests on exit
+const shutdownTimeout = 5 * time.Second
+
+// Serve initializes the MCP server and serves it over transport until
+// interrupted. addr is the listen address for the http and sse transports, or
+// the socket path for the unix transport; empty uses the default
+func (s *Server) Serve(transport, addr string) error {
+	if err := s.init(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var runErr error
+	switch transport {
+	case TransportStdio:
+		if s.verbose() {
+			log.Println("[MCP Server] Starting MCP server on stdio")
+		}
+		// Start the server (blocking)
+		runErr = s.defaultServer().Run(ctx, &mcp.StdioTransport{})
+	case TransportHTTP, TransportSSE:
+		if addr == "" {
+			addr = defaultHTTPAddr
+		}
+		handler, err := s.httpHandler(transport, addr)
+		if err != nil {
+			return err
+		}
+		runErr = s.listenAndServe(ctx, addr, handler, transport)
+	case TransportUnix:
+		if addr == "" {
+			addr = config.GetSocketPath()
+		}
+		listener, err := listenUnix(addr)
+		if err != nil {
+			return err
+		}
+		runErr = s.serveSocket(ctx, listener)
+	default:
+		return fmt.Errorf("unknown transport: %s (must be one of: stdio, http, sse, unix)", transport)
+	}
+
+	// Deliver anything still queued before exiting
+	_, noti := s.state()
+	if err := notifier.Flush(context.Background(), noti); err != nil {
+		log.Printf("[MCP Server] Failed to flush queued notifications: %v", err)
+	}
+
+	if runErr != nil && ctx.Err() == nil {
+		return fmt.Errorf("server error: %w", runErr)
+	}
+
+	return nil
+}
+
+// httpHandler returns the handler serving MCP over the streamable HTTP or SSE
+// transport on addr. Every client session shares this server's notifier. When
+// tokens are configured, requests must carry one of them as a bearer token.
+// Without tokens only loopback addresses are served, unless allowed explicitly
+func (s *Server) httpHandler(transport, addr string) (http.Handler, error) {
+	var handler http.Handler
+	switch transport {
+	case TransportHTTP:
+		handler = mcp.NewStreamableHTTPHandler(s.serverForRequest, nil)
+	case TransportSSE:
+		handler = mcp.NewSSEHandler(s.serverForRequest, nil)
+	default:
+		return nil, fmt.Errorf("transport %s is not served over HTTP", transport)
+	}
+
+	cfg, _ := s.state()
+	tokens, err := cfg.Server.Auth.LoadTokens()
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		if !isLoopback(addr) && !cfg.Server.Auth.AllowUnauthenticated {
+			return nil, fmt.Errorf("refusing to serve %s without auth tokens: configure server.auth.tokens, or set server.auth.allow_unauthenticated to let anyone who can reach it send notifications", addr)
+		}
+		log.Printf("[MCP Server] Warning: no auth tokens configured, anyone who can reach the server can send notifications")
+		return handler, nil
+	}
+
+	if s.verbose() {
+		log.Printf("[MCP Server] Requiring one of %d bearer tokens", len(tokens))
+	}
+	return requireToken(tokens, handler), nil
+}
+
+// isLoopback reports whether addr only listens on the loopback interface.
+// An empty host listens on every interface
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// listenAndServe serves handler on addr until ctx is done
+func (s *Server) listenAndServe(ctx context.Context, addr string, handler http.Handler, transport string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if s.verbose() {
+			log.Printf("[MCP Server] Starting MCP server on %s (%s)", addr, transport)
+		}
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// Streaming sessions stay open indefinitely, so close whatever is left after the timeout
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return httpServer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestHTTPTransports(t *testing.T) {
+	tests := []struct {
+		transport string
+		client    func(url string) mcp.Transport
+	}{
+		{TransportHTTP, func(url string) mcp.Transport { return &mcp.StreamableClientTransport{Endpoint: url} }},
+		{TransportSSE, func(url string) mcp.Transport { return &mcp.SSEClientTransport{Endpoint: url} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transport, func(t *testing.T) {
+			noti := &recordingNotifier{}
+			server := NewServer(config.DefaultConfig(), noti)
+			if err := server.init(); err != nil {
+				t.Fatalf("Failed to initialize server: %v", err)
+			}
+			handler, err := server.httpHandler(tt.transport, defaultHTTPAddr)
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+			httpServer := httptest.NewServer(handler)
+			defer httpServer.Close()
+
+			ctx := context.Background()
+			client := mcp.NewClient(&mcp.Implementation{Name: "remote-agent", Version: "0.1.0"}, nil)
+			session, err := client.Connect(ctx, tt.client(httpServer.URL), nil)
+			if err != nil {
+				t.Fatalf("Failed to connect over %s: %v", tt.transport, err)
+			}
+			defer session.Close()
+
+			if _, err := session.CallTool(ctx, &mcp.CallToolParams{
+				Name:      "poke",
+				Arguments: map[string]any{"message": "Deployed", "level": "success"},
+			}); err != nil {
+				t.Fatalf("poke failed: %v", err)
+			}
+			if noti.message != "Deployed" || noti.level != "success" {
+				t.Errorf("Expected the notification to reach the notifier, got %q [%s]", noti.message, noti.level)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_Stdio(t *testing.T) {
+	server := NewServer(config.DefaultConfig(), &recordingNotifier{})
+	if _, err := server.httpHandler(TransportStdio, defaultHTTPAddr); err == nil {
+		t.Error("Expected error for a transport not served over HTTP")
+	}
+}
+
+func TestServe_UnknownTransport(t *testing.T) {
+	server := NewServer(config.DefaultConfig(), &recordingNotifier{})
+	if err := server.Serve("carrier-pigeon", ""); err == nil {
+		t.Error("Expected error for unknown transport")
+	}
+}
+
+func TestHTTPHandler_RequiresTokensOffLoopback(t *testing.T) {
+	cfg := config.DefaultConfig()
+	server := NewServer(cfg, &recordingNotifier{})
+	if err := server.init(); err != nil {
+		t.Fatalf("Failed to initialize server: %v", err)
+	}
+
+	if _, err := server.httpHandler(TransportHTTP, "127.0.0.1:8787"); err != nil {
+		t.Errorf("Expected loopback to be served without tokens, got %v", err)
+	}
+	if _, err := server.httpHandler(TransportHTTP, "0.0.0.0:8787"); err == nil {
+		t.Error("Expected an error serving every interface without tokens")
+	}
+
+	cfg.Server.Auth.AllowUnauthenticated = true
+	if _, err := server.httpHandler(TransportHTTP, "0.0.0.0:8787"); err != nil {
+		t.Errorf("Expected the opt-out to allow serving without tokens, got %v", err)
+	}
+
+	cfg.Server.Auth.AllowUnauthenticated = false
+	cfg.Server.Auth.Tokens = []config.Token{{Name: "devbox", Token: "secret"}}
+	if _, err := server.httpHandler(TransportHTTP, "0.0.0.0:8787"); err != nil {
+		t.Errorf("Expected tokens to allow serving every interface, got %v", err)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr     string
+		loopback bool
+	}{
+		{"localhost:8787", true},
+		{"127.0.0.1:8787", true},
+		{"[::1]:8787", true},
+		{":8787", false},
+		{"0.0.0.0:8787", false},
+		{"192.168.1.10:8787", false},
+		{"devbox:8787", false},
+		{"localhost", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopback(tt.addr); got != tt.loopback {
+			t.Errorf("isLoopback(%q) = %v, want %v", tt.addr, got, tt.loopback)
+		}
+	}
+}
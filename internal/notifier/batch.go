@@ -0,0 +1,259 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// StatusQueued is reported for notifications held back to be part of a digest
+const StatusQueued = "queued for digest"
+
+// maxDigestLines is how many individual notifications a digest lists
+const maxDigestLines = 5
+
+// BatchNotifier coalesces bursts of notifications from the same workspace into a
+// single digest notification
+type BatchNotifier struct {
+	config *config.Config
+	next   Notifier
+	// digests is where digests are sent, next unless routed with RouteDigests
+	digests Notifier
+
+	mu      sync.Mutex
+	batches map[string]*batch
+	now     func() time.Time
+}
+
+// batch tracks recent arrivals and queued notifications of a workspace
+type batch struct {
+	arrivals []time.Time
+	pending  []Notification
+	timer    *time.Timer
+}
+
+// idle reports whether nothing is queued and no arrival falls within window
+func (b *batch) idle(now time.Time, window time.Duration) bool {
+	if b.timer != nil || len(b.pending) > 0 {
+		return false
+	}
+	for _, arrival := range b.arrivals {
+		if now.Sub(arrival) < window {
+			return false
+		}
+	}
+	return true
+}
+
+// NewBatchNotifier wraps next so that bursts are delivered as digests
+func NewBatchNotifier(cfg *config.Config, next Notifier) *BatchNotifier {
+	return &BatchNotifier{
+		config:  cfg,
+		next:    next,
+		batches: make(map[string]*batch),
+		now:     time.Now,
+	}
+}
+
+// Unwrap returns the notifier the digests are delivered through
+func (n *BatchNotifier) Unwrap() Notifier {
+	return n.next
+}
+
+// Send delivers the notification immediately unless its workspace is in a burst,
+// in which case it is queued and delivered in a digest once the window ends
+func (n *BatchNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	// Notifications waiting for the user's choice cannot be delayed
+	if len(notification.Actions) > 0 || isDigest(ctx) {
+		return n.next.Send(ctx, notification)
+	}
+
+	window := time.Duration(n.config.Notification.Batch.Window) * time.Second
+	workspace := notification.Workspace
+
+	n.mu.Lock()
+	now := n.now()
+	// Forget the workspaces that went quiet, so that every workspace ever seen is not kept
+	for name, b := range n.batches {
+		if b.idle(now, window) {
+			delete(n.batches, name)
+		}
+	}
+
+	b, ok := n.batches[workspace]
+	if !ok {
+		b = &batch{}
+		n.batches[workspace] = b
+	}
+
+	recent := b.arrivals[:0]
+	for _, arrival := range b.arrivals {
+		if now.Sub(arrival) < window {
+			recent = append(recent, arrival)
+		}
+	}
+	b.arrivals = append(recent, now)
+
+	if b.timer == nil && len(b.arrivals) <= n.config.Notification.Batch.Threshold {
+		n.mu.Unlock()
+		return n.next.Send(ctx, notification)
+	}
+
+	b.pending = append(b.pending, notification)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(window, func() { n.flushWorkspace(workspace) })
+	}
+	queued := len(b.pending)
+	n.mu.Unlock()
+
+	if n.config.Notification.Verbose {
+		log.Printf("[BatchNotifier] Queued notification for digest (%d pending) - Title: %s, Workspace: %s",
+			queued, notification.Title, workspace)
+	}
+
+	return Receipt{Status: StatusQueued}, nil
+}
+
+// sendDigestsThrough sends the digests through top instead of the wrapped notifier
+func (n *BatchNotifier) sendDigestsThrough(top Notifier) {
+	n.digests = top
+}
+
+// Update passes the update on. It replaces a single notification, so it is
+// never part of a burst
+func (n *BatchNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	return Update(ctx, n.next, id, notification, percent)
+}
+
+// Close closes the notification through the wrapped notifier
+func (n *BatchNotifier) Close(ctx context.Context, id string) error {
+	return Close(ctx, n.next, id)
+}
+
+// Flush delivers all queued notifications as digests right away
+func (n *BatchNotifier) Flush(ctx context.Context) error {
+	n.mu.Lock()
+	workspaces := make([]string, 0, len(n.batches))
+	for workspace := range n.batches {
+		workspaces = append(workspaces, workspace)
+	}
+	n.mu.Unlock()
+
+	var errs []error
+	for _, workspace := range workspaces {
+		if err := n.deliver(ctx, workspace); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushWorkspace delivers the digest of a workspace when its window ends
+func (n *BatchNotifier) flushWorkspace(workspace string) {
+	if err := n.deliver(context.Background(), workspace); err != nil && n.config.Notification.Verbose {
+		log.Printf("[BatchNotifier] Failed to deliver digest: %v", err)
+	}
+}
+
+// deliver sends the queued notifications of a workspace as one digest
+func (n *BatchNotifier) deliver(ctx context.Context, workspace string) error {
+	n.mu.Lock()
+	b, ok := n.batches[workspace]
+	if !ok || len(b.pending) == 0 {
+		n.mu.Unlock()
+		return nil
+	}
+	pending := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	// A workspace still in its burst keeps its arrivals, so that what it sends
+	// next is queued for another digest
+	window := time.Duration(n.config.Notification.Batch.Window) * time.Second
+	if b.idle(n.now(), window) {
+		delete(n.batches, workspace)
+	}
+	n.mu.Unlock()
+
+	digest := buildDigest(n.config, workspace, pending)
+	if n.config.Notification.Verbose {
+		log.Printf("[BatchNotifier] Delivering digest of %d notifications - Title: %s", len(pending), digest.Title)
+	}
+
+	if _, err := sendDigest(ctx, n.digests, n.next, digest); err != nil {
+		return fmt.Errorf("failed to deliver digest: %w", err)
+	}
+	return nil
+}
+
+// buildDigest summarizes queued notifications, using the most urgent level so the
+// digest gets that level's icon and urgency
+func buildDigest(cfg *config.Config, workspace string, pending []Notification) Notification {
+	if len(pending) == 1 {
+		return pending[0]
+	}
+
+	counts := make(map[string]int)
+	for _, notification := range pending {
+		counts[notification.Level]++
+	}
+
+	levels := make([]string, 0, len(counts))
+	for level := range counts {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if counts[levels[i]] != counts[levels[j]] {
+			return counts[levels[i]] > counts[levels[j]]
+		}
+		return levels[i] < levels[j]
+	})
+
+	highest := levels[0]
+	for _, level := range levels[1:] {
+		if levelUrgency(cfg, level) > levelUrgency(cfg, highest) {
+			highest = level
+		}
+	}
+
+	summary := make([]string, len(levels))
+	for i, level := range levels {
+		summary[i] = fmt.Sprintf("%d %s", counts[level], level)
+	}
+
+	lines := []string{strings.Join(summary, ", ")}
+	for i, notification := range pending {
+		if i == maxDigestLines {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(pending)-maxDigestLines))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", notification.Title, notification.Message))
+	}
+
+	source := workspace
+	if source == "" {
+		source = getAppName()
+	}
+
+	return Notification{
+		Title:     fmt.Sprintf("%d updates from %s", len(pending), source),
+		Message:   strings.Join(lines, "\n"),
+		Level:     highest,
+		Workspace: workspace,
+		Client:    pending[len(pending)-1].Client,
+	}
+}
+
+// levelUrgency returns the freedesktop urgency value configured for a level
+func levelUrgency(cfg *config.Config, level string) byte {
+	return urgencyByte(cfg.Notification.Levels[level].Urgency)
+}
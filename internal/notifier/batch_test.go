@@ -0,0 +1,242 @@
+package notifier
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
+)
+
+// collectingNotifier records every notification it delivers
+type collectingNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (c *collectingNotifier) Send(ctx context.Context, n Notification) (Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, n)
+	return Receipt{}, nil
+}
+
+func (c *collectingNotifier) delivered() []Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Notification(nil), c.sent...)
+}
+
+func newTestBatchNotifier(threshold, window int) (*BatchNotifier, *collectingNotifier) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Batch = config.BatchConfig{Enabled: true, Threshold: threshold, Window: window}
+	next := &collectingNotifier{}
+	return NewBatchNotifier(cfg, next), next
+}
+
+func TestBatchNotifier_BelowThreshold(t *testing.T) {
+	batcher, next := newTestBatchNotifier(3, 60)
+
+	for i := 0; i < 3; i++ {
+		if status := sendStatus(t, batcher, Notification{Title: "Step", Message: "done", Level: "info", Workspace: "projects/foo"}); status != "" {
+			t.Fatalf("Expected notification %d to be delivered immediately, got %q", i+1, status)
+		}
+	}
+	if len(next.delivered()) != 3 {
+		t.Errorf("Expected 3 immediate deliveries, got %d", len(next.delivered()))
+	}
+}
+
+func TestBatchNotifier_BurstDigest(t *testing.T) {
+	batcher, next := newTestBatchNotifier(1, 60)
+
+	burst := []Notification{
+		{Title: "Build", Message: "started", Level: "info"},
+		{Title: "Lint", Message: "passed", Level: "success"},
+		{Title: "Tests", Message: "passed", Level: "success"},
+		{Title: "Coverage", Message: "dropped", Level: "warning"},
+		{Title: "Docs", Message: "built", Level: "success"},
+		{Title: "Bench", Message: "slower", Level: "warning"},
+	}
+	for i, n := range burst {
+		n.Workspace = "projects/foo"
+		status := sendStatus(t, batcher, n)
+		if i == 0 && status != "" {
+			t.Errorf("Expected first notification to be delivered, got %q", status)
+		}
+		if i > 0 && status != StatusQueued {
+			t.Errorf("Expected notification %d to be queued, got %q", i+1, status)
+		}
+	}
+
+	if err := Flush(context.Background(), batcher); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	delivered := next.delivered()
+	if len(delivered) != 2 {
+		t.Fatalf("Expected the first notification and one digest, got %d", len(delivered))
+	}
+
+	digest := delivered[1]
+	if digest.Title != "5 updates from projects/foo" {
+		t.Errorf("Unexpected digest title: %q", digest.Title)
+	}
+	if !strings.HasPrefix(digest.Message, "3 success, 2 warning") {
+		t.Errorf("Unexpected digest summary: %q", digest.Message)
+	}
+	if !strings.Contains(digest.Message, "Coverage: dropped") {
+		t.Errorf("Expected digest to list queued notifications, got %q", digest.Message)
+	}
+	// warning has the highest urgency among success (low) and warning (normal)
+	if digest.Level != "warning" {
+		t.Errorf("Expected digest level 'warning', got %q", digest.Level)
+	}
+
+	// Nothing left to flush
+	if err := batcher.Flush(context.Background()); err != nil {
+		t.Fatalf("Second flush failed: %v", err)
+	}
+	if len(next.delivered()) != 2 {
+		t.Error("Expected no further deliveries after the queue was flushed")
+	}
+}
+
+func TestBatchNotifier_DigestTruncates(t *testing.T) {
+	batcher, _ := newTestBatchNotifier(0, 60)
+
+	var pending []Notification
+	for i := 0; i < 8; i++ {
+		pending = append(pending, Notification{Title: "Step", Message: "done", Level: "error"})
+	}
+
+	digest := buildDigest(batcher.config, "projects/foo", pending)
+	if !strings.Contains(digest.Message, "…and 3 more") {
+		t.Errorf("Expected truncated digest, got %q", digest.Message)
+	}
+	if digest.Level != "error" {
+		t.Errorf("Expected digest level 'error', got %q", digest.Level)
+	}
+}
+
+func TestBatchNotifier_SeparateWorkspaces(t *testing.T) {
+	batcher, next := newTestBatchNotifier(1, 60)
+
+	sendStatus(t, batcher, Notification{Title: "A1", Message: "m", Level: "info", Workspace: "projects/a"})
+	if status := sendStatus(t, batcher, Notification{Title: "B1", Message: "m", Level: "info", Workspace: "projects/b"}); status != "" {
+		t.Errorf("Expected another workspace to have its own burst window, got %q", status)
+	}
+	if status := sendStatus(t, batcher, Notification{Title: "A2", Message: "m", Level: "info", Workspace: "projects/a"}); status != StatusQueued {
+		t.Errorf("Expected second notification from projects/a to be queued, got %q", status)
+	}
+
+	batcher.Flush(context.Background())
+
+	// A single queued notification is delivered as is
+	delivered := next.delivered()
+	if last := delivered[len(delivered)-1]; last.Title != "A2" {
+		t.Errorf("Expected single queued notification to be delivered unchanged, got %q", last.Title)
+	}
+}
+
+func TestBatchNotifier_ForgetsQuietWorkspaces(t *testing.T) {
+	batcher, next := newTestBatchNotifier(1, 60)
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	batcher.now = func() time.Time { return now }
+
+	for _, title := range []string{"Build", "Tests"} {
+		sendStatus(t, batcher, Notification{Title: title, Message: "m", Level: "info", Workspace: "projects/foo"})
+	}
+	for _, workspace := range []string{"projects/a", "projects/b", "projects/c"} {
+		sendStatus(t, batcher, Notification{Title: "Done", Message: "m", Level: "info", Workspace: workspace})
+	}
+
+	// A burst still within its window is remembered after its digest
+	if err := Flush(context.Background(), batcher); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, ok := batcher.batches["projects/foo"]; !ok {
+		t.Error("Expected the workspace to be kept while its burst lasts")
+	}
+
+	// Once the window has passed, only the workspace sending now is kept
+	now = now.Add(2 * time.Minute)
+	sendStatus(t, batcher, Notification{Title: "Deploy", Message: "m", Level: "info", Workspace: "projects/bar"})
+	if len(batcher.batches) != 1 {
+		t.Errorf("Expected only the active workspace to be kept, got %d", len(batcher.batches))
+	}
+
+	// And a digest sent after its burst ended forgets the workspace
+	sendStatus(t, batcher, Notification{Title: "Step 2", Message: "m", Level: "info", Workspace: "projects/bar"})
+	now = now.Add(2 * time.Minute)
+	if err := Flush(context.Background(), batcher); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(batcher.batches) != 0 {
+		t.Errorf("Expected no workspaces to be kept, got %d", len(batcher.batches))
+	}
+	if len(next.delivered()) != 7 {
+		t.Errorf("Expected 7 deliveries, got %d", len(next.delivered()))
+	}
+}
+
+func TestBatchNotifier_ActionsBypassBatching(t *testing.T) {
+	batcher, next := newTestBatchNotifier(0, 60)
+
+	if status := sendStatus(t, batcher, Notification{Title: "Deploy?", Message: "m", Level: "warning", Actions: []string{"Yes"}}); status != "" {
+		t.Errorf("Expected notification with actions to be delivered immediately, got %q", status)
+	}
+	if len(next.delivered()) != 1 {
+		t.Error("Expected notification with actions to reach the wrapped notifier")
+	}
+}
+
+func TestBatchNotifier_WindowTimer(t *testing.T) {
+	batcher, next := newTestBatchNotifier(0, 1)
+
+	sendStatus(t, batcher, Notification{Title: "One", Message: "m", Level: "info"})
+	sendStatus(t, batcher, Notification{Title: "Two", Message: "m", Level: "info"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(next.delivered()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Digest was not delivered when the window ended")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if digest := next.delivered()[0]; !strings.HasPrefix(digest.Title, "2 updates from") {
+		t.Errorf("Unexpected digest title: %q", digest.Title)
+	}
+}
+
+func TestFlush_ThroughWrappers(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Batch = config.BatchConfig{Enabled: true, Threshold: 0, Window: 60}
+	next := &collectingNotifier{}
+
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	chain := NewHistoryNotifier(cfg, NewRateLimitNotifier(cfg, NewBatchNotifier(cfg, next)), store)
+
+	if status := sendStatus(t, chain, Notification{Title: "Queued", Message: "m", Level: "info"}); status != StatusQueued {
+		t.Fatalf("Expected notification to be queued, got %q", status)
+	}
+	if err := Flush(context.Background(), chain); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(next.delivered()) != 1 {
+		t.Errorf("Expected Flush to reach the batch notifier through the wrappers")
+	}
+
+	entries, _ := store.List(history.Filter{})
+	if len(entries) != 1 || entries[0].Status != StatusQueued {
+		t.Errorf("Expected history to record the queued status, got %+v", entries)
+	}
+}
@@ -0,0 +1,161 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// Command defaults
+const (
+	defaultCommandTimeout       = 10 * time.Second
+	defaultCommandMaxConcurrent = 4
+)
+
+// maxStderr is how much of a command's stderr is kept for logs and errors
+const maxStderr = 4096
+
+// commandData is what argument templates can refer to
+type commandData struct {
+	Title     string
+	Message   string
+	Level     string
+	Urgency   string
+	Icon      string
+	Workspace string
+	Client    string
+}
+
+// CommandNotifier runs a configured program for every notification, passing
+// the notification through templated arguments and POKE_* environment variables
+type CommandNotifier struct {
+	config  *config.Config
+	args    []*template.Template
+	timeout time.Duration
+	// slots limits how many commands run at once
+	slots chan struct{}
+}
+
+// NewCommandNotifier creates a notifier running the configured command
+func NewCommandNotifier(cfg *config.Config, command config.CommandConfig) (*CommandNotifier, error) {
+	args := make([]*template.Template, len(command.Args))
+	for i, arg := range command.Args {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command argument template %q: %w", arg, err)
+		}
+		args[i] = tmpl
+	}
+
+	timeout := defaultCommandTimeout
+	if command.Timeout > 0 {
+		timeout = time.Duration(command.Timeout) * time.Second
+	}
+	maxConcurrent := defaultCommandMaxConcurrent
+	if command.MaxConcurrent > 0 {
+		maxConcurrent = command.MaxConcurrent
+	}
+
+	return &CommandNotifier{
+		config:  cfg,
+		args:    args,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrent),
+	}, nil
+}
+
+// Send runs the command and waits for it to finish
+func (n *CommandNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	level := n.config.Notification.Levels[notification.Level]
+	data := commandData{
+		Title:     notification.Title,
+		Message:   notification.Message,
+		Level:     notification.Level,
+		Urgency:   level.Urgency,
+		Icon:      level.Icon,
+		Workspace: notification.Workspace,
+		Client:    notification.Client,
+	}
+
+	argv := make([]string, len(n.args))
+	for i, tmpl := range n.args {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return Receipt{}, fmt.Errorf("failed to render command argument: %w", err)
+		}
+		argv[i] = buf.String()
+	}
+
+	// Wait for a free slot so a burst of notifications cannot fork without bound
+	select {
+	case n.slots <- struct{}{}:
+		defer func() { <-n.slots }()
+	case <-ctx.Done():
+		return Receipt{}, fmt.Errorf("gave up waiting to run command: %w", ctx.Err())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	if n.config.Notification.Verbose {
+		log.Printf("[CommandNotifier] Running %q - Title: %s, Level: %s", argv, notification.Title, notification.Level)
+	}
+
+	var stderr limitedBuffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = append(os.Environ(),
+		"POKE_TITLE="+data.Title,
+		"POKE_MESSAGE="+data.Message,
+		"POKE_LEVEL="+data.Level,
+		"POKE_URGENCY="+data.Urgency,
+		"POKE_ICON="+data.Icon,
+		"POKE_WORKSPACE="+data.Workspace,
+		"POKE_CLIENT="+data.Client,
+	)
+	cmd.Stderr = &stderr
+	// Do not hang on children that keep stderr open after the command is killed
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	output := strings.TrimSpace(stderr.String())
+	if output != "" && n.config.Notification.Verbose {
+		log.Printf("[CommandNotifier] %s stderr: %s", argv[0], output)
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Receipt{}, fmt.Errorf("command %s timed out after %s", argv[0], n.timeout)
+		}
+		if output != "" {
+			return Receipt{}, fmt.Errorf("command %s failed: %w: %s", argv[0], err, output)
+		}
+		return Receipt{}, fmt.Errorf("command %s failed: %w", argv[0], err)
+	}
+
+	return Receipt{}, nil
+}
+
+// limitedBuffer keeps the first maxStderr bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxStderr - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
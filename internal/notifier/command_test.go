@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// newTestCommandNotifier runs a shell script, skipping the test without a shell
+func newTestCommandNotifier(t *testing.T, command config.CommandConfig) *CommandNotifier {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("Skipping command test: sh not available")
+	}
+
+	notifier, err := NewCommandNotifier(config.DefaultConfig(), command)
+	if err != nil {
+		t.Fatalf("Failed to create command notifier: %v", err)
+	}
+	return notifier
+}
+
+func TestCommandNotifier_ArgsAndEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	notifier := newTestCommandNotifier(t, config.CommandConfig{
+		Args: []string{"sh", "-c", `printf '%s|%s|%s|%s' "$1" "$2" "$POKE_MESSAGE" "$POKE_URGENCY" > "$3"`,
+			"sh", "{{.Level}}: {{.Title}}", "{{.Workspace}}", out},
+	})
+
+	_, err := notifier.Send(context.Background(), Notification{
+		Title:     "Build failed",
+		Message:   "3 tests failing",
+		Level:     "error",
+		Workspace: "projects/foo",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Command did not run: %v", err)
+	}
+	expected := "error: Build failed|projects/foo|3 tests failing|critical"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestCommandNotifier_FailureIncludesStderr(t *testing.T) {
+	notifier := newTestCommandNotifier(t, config.CommandConfig{
+		Args: []string{"sh", "-c", "echo 'no such sound' >&2; exit 3"},
+	})
+
+	_, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"})
+	if err == nil {
+		t.Fatal("Expected error for a failing command")
+	}
+	if !strings.Contains(err.Error(), "no such sound") {
+		t.Errorf("Expected stderr in the error, got: %v", err)
+	}
+}
+
+func TestCommandNotifier_Timeout(t *testing.T) {
+	notifier := newTestCommandNotifier(t, config.CommandConfig{
+		Args:    []string{"sh", "-c", "sleep 10"},
+		Timeout: 1,
+	})
+
+	start := time.Now()
+	_, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to be killed after about 1s, took %s", elapsed)
+	}
+}
+
+func TestCommandNotifier_ConcurrencyCap(t *testing.T) {
+	notifier := newTestCommandNotifier(t, config.CommandConfig{
+		Args:          []string{"sh", "-c", "sleep 2"},
+		MaxConcurrent: 1,
+	})
+
+	go notifier.Send(context.Background(), Notification{Title: "First", Message: "M", Level: "info"})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(notifier.slots) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("First command did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := notifier.Send(ctx, Notification{Title: "Second", Message: "M", Level: "info"})
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting") {
+		t.Errorf("Expected the second command to wait for a free slot, got: %v", err)
+	}
+}
+
+func TestNewCommandNotifier_InvalidTemplate(t *testing.T) {
+	if _, err := NewCommandNotifier(config.DefaultConfig(), config.CommandConfig{Args: []string{"echo", "{{.Title"}}); err == nil {
+		t.Error("Expected error for an invalid argument template")
+	}
+}
@@ -0,0 +1,335 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus coordinates of the freedesktop notification service
+const (
+	dbusNotificationsName      = "org.freedesktop.Notifications"
+	dbusNotificationsPath      = dbus.ObjectPath("/org/freedesktop/Notifications")
+	dbusNotificationsInterface = "org.freedesktop.Notifications"
+)
+
+// DBusNotifier sends notifications directly to org.freedesktop.Notifications,
+// so urgency, icon, expire timeout and category reach the notification daemon
+type DBusNotifier struct {
+	config  *config.Config
+	conn    *dbus.Conn
+	appName string
+	ids     *dbusIDs
+}
+
+// dbusIDs maps the ids of notifications updated in place to daemon ids
+type dbusIDs struct {
+	mu     sync.Mutex
+	daemon map[string]uint32
+}
+
+// sessionBus is the connection shared by the desktop notifiers NewNotifier
+// creates, so that reloading the configuration neither opens a new connection
+// nor forgets which notifications progress updates replace
+var sessionBus struct {
+	mu   sync.Mutex
+	conn *dbus.Conn
+	ids  *dbusIDs
+}
+
+// NewDBusNotifier creates a notifier talking to the notification daemon over conn
+func NewDBusNotifier(cfg *config.Config, conn *dbus.Conn) *DBusNotifier {
+	return &DBusNotifier{
+		config:  cfg,
+		conn:    conn,
+		appName: getAppName(),
+		ids:     &dbusIDs{daemon: make(map[string]uint32)},
+	}
+}
+
+// newSessionDBusNotifier creates a notifier on the shared session bus
+// connection, connecting first if there is none or it was lost
+func newSessionDBusNotifier(cfg *config.Config) (*DBusNotifier, error) {
+	sessionBus.mu.Lock()
+	defer sessionBus.mu.Unlock()
+
+	if sessionBus.conn == nil || !sessionBus.conn.Connected() {
+		conn, err := connectSessionBus()
+		if err != nil {
+			return nil, err
+		}
+		sessionBus.conn = conn
+	}
+	if sessionBus.ids == nil {
+		sessionBus.ids = &dbusIDs{daemon: make(map[string]uint32)}
+	}
+
+	notifier := NewDBusNotifier(cfg, sessionBus.conn)
+	notifier.ids = sessionBus.ids
+	return notifier, nil
+}
+
+// connectSessionBus connects to an already running session bus without autolaunching one
+func connectSessionBus() (*dbus.Conn, error) {
+	conn, err := dbus.SessionBusPrivateNoAutoStartup()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Send sends a notification through the org.freedesktop.Notifications Notify method.
+// When the notification has actions, the receipt reports the user's choice until ctx is done
+func (n *DBusNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	// Subscribe before sending so a quick click cannot be missed
+	var signals chan *dbus.Signal
+	if len(notification.Actions) > 0 {
+		if err := n.conn.AddMatchSignal(n.signalMatch()...); err != nil {
+			return Receipt{}, fmt.Errorf("failed to subscribe to notification signals: %w", err)
+		}
+		signals = make(chan *dbus.Signal, 8)
+		n.conn.Signal(signals)
+	}
+
+	id, err := n.notify(ctx, notification, 0, -1)
+	if err != nil {
+		if signals != nil {
+			n.unsubscribe(signals)
+		}
+		return Receipt{}, err
+	}
+
+	receipt := Receipt{ID: strconv.FormatUint(uint64(id), 10)}
+	if signals == nil {
+		return receipt, nil
+	}
+
+	responses := make(chan Response, 1)
+	go n.waitForResponse(ctx, id, notification.Actions, signals, responses)
+	receipt.Response = responses
+	return receipt, nil
+}
+
+// Update shows the notification in place of the one previously sent with id,
+// with the percentage as the "value" hint
+func (n *DBusNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	n.ids.mu.Lock()
+	replacesID := n.ids.daemon[id]
+	n.ids.mu.Unlock()
+
+	notification.Actions = nil
+	dbusID, err := n.notify(ctx, notification, replacesID, percent)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	n.ids.mu.Lock()
+	n.ids.daemon[id] = dbusID
+	n.ids.mu.Unlock()
+	return Receipt{ID: id}, nil
+}
+
+// Close closes the notification updated in place with id, or the one whose
+// receipt had id
+func (n *DBusNotifier) Close(ctx context.Context, id string) error {
+	n.ids.mu.Lock()
+	dbusID, ok := n.ids.daemon[id]
+	delete(n.ids.daemon, id)
+	n.ids.mu.Unlock()
+
+	if !ok {
+		parsed, err := strconv.ParseUint(id, 10, 32)
+		if err != nil || parsed == 0 {
+			return fmt.Errorf("unknown notification: %s", id)
+		}
+		dbusID = uint32(parsed)
+	}
+
+	if n.config.Notification.Verbose {
+		log.Printf("[DBusNotifier] Closing notification %s (id %d)", id, dbusID)
+	}
+
+	obj := n.conn.Object(dbusNotificationsName, dbusNotificationsPath)
+	if err := obj.CallWithContext(ctx, dbusNotificationsInterface+".CloseNotification", 0, dbusID).Err; err != nil {
+		return fmt.Errorf("failed to close notification: %w", err)
+	}
+	return nil
+}
+
+// notify calls Notify, replacing notification replacesID unless it is 0, and
+// returns the id the notification daemon assigned
+func (n *DBusNotifier) notify(ctx context.Context, notification Notification, replacesID uint32, percent int) (uint32, error) {
+	levelConfig := n.config.Notification.Levels[notification.Level]
+
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(urgencyByte(levelConfig.Urgency)),
+	}
+	if levelConfig.Category != "" {
+		hints["category"] = dbus.MakeVariant(levelConfig.Category)
+	}
+	if percent >= 0 {
+		hints["value"] = dbus.MakeVariant(int32(min(percent, 100)))
+	}
+
+	// Actions are sent as key/label pairs. Generated keys keep a label such as
+	// "default" from becoming the action of a click on the notification
+	actions := []string{}
+	for i, action := range notification.Actions {
+		actions = append(actions, actionKey(i), action)
+	}
+
+	expireTimeout := expireTimeoutMillis(levelConfig.ExpireTimeout)
+
+	appName := notification.Workspace
+	if appName == "" {
+		appName = n.appName
+	}
+
+	if n.config.Notification.Verbose {
+		log.Printf("[DBusNotifier] Sending notification - Title: %s, Message: %s, Level: %s, Icon: %s, Urgency: %s, Category: %s, Timeout: %d, Actions: %v, Replaces: %d, Progress: %d",
+			notification.Title, notification.Message, notification.Level, levelConfig.Icon, levelConfig.Urgency,
+			levelConfig.Category, expireTimeout, notification.Actions, replacesID, percent)
+	}
+
+	obj := n.conn.Object(dbusNotificationsName, dbusNotificationsPath)
+	call := obj.CallWithContext(ctx, dbusNotificationsInterface+".Notify", 0,
+		appName, replacesID, levelConfig.Icon, notification.Title, notification.Message, actions, hints, expireTimeout)
+
+	var id uint32
+	err := call.Err
+	if err == nil {
+		err = call.Store(&id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	if n.config.Notification.Verbose {
+		log.Printf("[DBusNotifier] Notification delivered with id %d", id)
+	}
+	return id, nil
+}
+
+// actionKey returns the key the action at index is sent with
+func actionKey(index int) string {
+	return fmt.Sprintf("action-%d", index)
+}
+
+// actionLabel returns the label of the action out of actions sent with key
+func actionLabel(actions []string, key string) (string, bool) {
+	for i, label := range actions {
+		if actionKey(i) == key {
+			return label, true
+		}
+	}
+	return "", false
+}
+
+// waitForResponse forwards the ActionInvoked or NotificationClosed signal for
+// notification id, with the label of the invoked action out of actions,
+// closing responses once done
+func (n *DBusNotifier) waitForResponse(ctx context.Context, id uint32, actions []string, signals chan *dbus.Signal, responses chan<- Response) {
+	defer close(responses)
+	defer n.unsubscribe(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case signal, ok := <-signals:
+			if !ok {
+				return
+			}
+			if len(signal.Body) < 2 {
+				continue
+			}
+			if signalID, ok := signal.Body[0].(uint32); !ok || signalID != id {
+				continue
+			}
+
+			switch signal.Name {
+			case dbusNotificationsInterface + ".ActionInvoked":
+				key, _ := signal.Body[1].(string)
+				label, ok := actionLabel(actions, key)
+				if !ok {
+					// Some servers report a click on the notification as "default"
+					continue
+				}
+				responses <- Response{Action: label}
+				return
+			case dbusNotificationsInterface + ".NotificationClosed":
+				reason, _ := signal.Body[1].(uint32)
+				responses <- Response{CloseReason: closeReason(reason)}
+				return
+			}
+		}
+	}
+}
+
+// signalMatch returns the match rule for signals emitted by the notification service
+func (n *DBusNotifier) signalMatch() []dbus.MatchOption {
+	return []dbus.MatchOption{
+		dbus.WithMatchObjectPath(dbusNotificationsPath),
+		dbus.WithMatchInterface(dbusNotificationsInterface),
+	}
+}
+
+// unsubscribe stops delivering notification signals to signals
+func (n *DBusNotifier) unsubscribe(signals chan *dbus.Signal) {
+	n.conn.RemoveSignal(signals)
+	if err := n.conn.RemoveMatchSignal(n.signalMatch()...); err != nil && n.config.Notification.Verbose {
+		log.Printf("[DBusNotifier] Failed to remove signal match: %v", err)
+	}
+}
+
+// closeReason describes the reason code of a NotificationClosed signal
+func closeReason(reason uint32) string {
+	switch reason {
+	case 1:
+		return "expired"
+	case 2:
+		return "dismissed"
+	case 3:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// urgencyByte maps a configured urgency name to the freedesktop urgency hint value
+func urgencyByte(urgency string) byte {
+	switch urgency {
+	case "low":
+		return 0
+	case "critical":
+		return 2
+	default:
+		return 1 // normal
+	}
+}
+
+// expireTimeoutMillis maps the configured expire timeout to the Notify argument,
+// where -1 means server default and 0 means never expire
+func expireTimeoutMillis(timeout int) int32 {
+	switch {
+	case timeout == 0:
+		return -1
+	case timeout < 0:
+		return 0
+	default:
+		return int32(timeout)
+	}
+}
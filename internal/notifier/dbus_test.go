@@ -0,0 +1,482 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/godbus/dbus/v5"
+)
+
+// notifyCall records the arguments of a Notify call received by the fake server
+type notifyCall struct {
+	AppName       string
+	ReplacesID    uint32
+	AppIcon       string
+	Summary       string
+	Body          string
+	Actions       []string
+	Hints         map[string]dbus.Variant
+	ExpireTimeout int32
+}
+
+// fakeNotificationServer implements org.freedesktop.Notifications for tests
+type fakeNotificationServer struct {
+	mu     sync.Mutex
+	conn   *dbus.Conn
+	calls  []notifyCall
+	closed []uint32
+}
+
+func (f *fakeNotificationServer) Notify(appName string, replacesID uint32, appIcon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, notifyCall{
+		AppName:       appName,
+		ReplacesID:    replacesID,
+		AppIcon:       appIcon,
+		Summary:       summary,
+		Body:          body,
+		Actions:       actions,
+		Hints:         hints,
+		ExpireTimeout: expireTimeout,
+	})
+	// Like real daemons, a replaced notification keeps its id
+	if replacesID != 0 {
+		return replacesID, nil
+	}
+	return uint32(len(f.calls)), nil
+}
+
+func (f *fakeNotificationServer) CloseNotification(id uint32) *dbus.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = append(f.closed, id)
+	return nil
+}
+
+// emit sends a notification signal from the fake server
+func (f *fakeNotificationServer) emit(t *testing.T, member string, values ...interface{}) {
+	t.Helper()
+	if err := f.conn.Emit(dbusNotificationsPath, dbusNotificationsInterface+"."+member, values...); err != nil {
+		t.Fatalf("Failed to emit %s: %v", member, err)
+	}
+}
+
+func (f *fakeNotificationServer) lastCall(t *testing.T) notifyCall {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.calls) == 0 {
+		t.Fatal("Expected the fake server to receive a Notify call")
+	}
+	return f.calls[len(f.calls)-1]
+}
+
+// startTestBus launches a private dbus-daemon and returns its address
+func startTestBus(t *testing.T) string {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("Skipping D-Bus test: dbus-daemon not available")
+	}
+
+	cmd := exec.Command(daemon, "--session", "--nofork", "--nopidfile", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get dbus-daemon stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("Skipping D-Bus test: failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read dbus-daemon address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// connectTestBus opens a connection to the test bus, closed when the test ends
+func connectTestBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("Failed to connect to test bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// registerFakeServer exports a fake notification server on the test bus
+func registerFakeServer(t *testing.T, address string) *fakeNotificationServer {
+	t.Helper()
+
+	conn := connectTestBus(t, address)
+	fake := &fakeNotificationServer{conn: conn}
+	if err := conn.Export(fake, dbusNotificationsPath, dbusNotificationsInterface); err != nil {
+		t.Fatalf("Failed to export fake server: %v", err)
+	}
+	reply, err := conn.RequestName(dbusNotificationsName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Failed to own %s on test bus: %v", dbusNotificationsName, err)
+	}
+	return fake
+}
+
+func TestDBusNotifier_Send(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	cfg := config.DefaultConfig()
+	notifier := NewDBusNotifier(cfg, conn)
+	notifier.appName = "workspace/foo"
+
+	if _, err := notifier.Send(context.Background(), Notification{Title: "Build failed", Message: "3 tests failing", Level: "error"}); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	call := fake.lastCall(t)
+	if call.AppName != "workspace/foo" {
+		t.Errorf("Expected app name 'workspace/foo', got %q", call.AppName)
+	}
+	if call.Summary != "Build failed" || call.Body != "3 tests failing" {
+		t.Errorf("Unexpected summary/body: %q / %q", call.Summary, call.Body)
+	}
+	if call.AppIcon != "dialog-error" {
+		t.Errorf("Expected icon 'dialog-error', got %q", call.AppIcon)
+	}
+	if urgency, ok := call.Hints["urgency"].Value().(byte); !ok || urgency != 2 {
+		t.Errorf("Expected critical urgency hint (2), got %v", call.Hints["urgency"])
+	}
+	if _, ok := call.Hints["category"]; ok {
+		t.Error("Expected no category hint when none is configured")
+	}
+	if call.ExpireTimeout != -1 {
+		t.Errorf("Expected server default expire timeout (-1), got %d", call.ExpireTimeout)
+	}
+}
+
+func TestDBusNotifier_SendLevelOptions(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	cfg := config.DefaultConfig()
+	cfg.Notification.Levels["success"] = config.Level{
+		Urgency:       "low",
+		Icon:          "emblem-default",
+		ExpireTimeout: 5000,
+		Category:      "transfer.complete",
+	}
+	notifier := NewDBusNotifier(cfg, conn)
+
+	if _, err := notifier.Send(context.Background(), Notification{Title: "Done", Message: "Upload finished", Level: "success"}); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	call := fake.lastCall(t)
+	if urgency, ok := call.Hints["urgency"].Value().(byte); !ok || urgency != 0 {
+		t.Errorf("Expected low urgency hint (0), got %v", call.Hints["urgency"])
+	}
+	if category, ok := call.Hints["category"].Value().(string); !ok || category != "transfer.complete" {
+		t.Errorf("Expected category 'transfer.complete', got %v", call.Hints["category"])
+	}
+	if call.ExpireTimeout != 5000 {
+		t.Errorf("Expected expire timeout 5000, got %d", call.ExpireTimeout)
+	}
+	if call.AppIcon != "emblem-default" {
+		t.Errorf("Expected icon 'emblem-default', got %q", call.AppIcon)
+	}
+}
+
+func TestDBusNotifier_NoServer(t *testing.T) {
+	// No fake server is registered, so there is no notification daemon on the bus
+	address := startTestBus(t)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	if _, err := notifier.Send(context.Background(), Notification{Title: "Title", Message: "Message", Level: "info"}); err == nil {
+		t.Error("Expected error when no notification daemon is running")
+	}
+}
+
+func TestDBusNotifier_ActionInvoked(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receipt, err := notifier.Send(ctx, Notification{
+		Title:   "Deploy",
+		Message: "Deploy to production?",
+		Level:   "warning",
+		Actions: []string{"Approve", "default"},
+	})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if receipt.Response == nil {
+		t.Fatal("Expected a response channel for a notification with actions")
+	}
+
+	call := fake.lastCall(t)
+	// A "default" label must not become the action of a click on the notification
+	expectedActions := []string{"action-0", "Approve", "action-1", "default"}
+	if strings.Join(call.Actions, ",") != strings.Join(expectedActions, ",") {
+		t.Errorf("Expected actions %v, got %v", expectedActions, call.Actions)
+	}
+
+	// A signal for another notification and a click on the notification must be ignored
+	fake.emit(t, "ActionInvoked", uint32(999), "action-0")
+	fake.emit(t, "ActionInvoked", uint32(1), "default")
+	fake.emit(t, "ActionInvoked", uint32(1), "action-1")
+
+	select {
+	case response := <-receipt.Response:
+		if response.Action != "default" {
+			t.Errorf("Expected the label of the second action, got %+v", response)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for action response")
+	}
+}
+
+func TestDBusNotifier_NotificationClosed(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receipt, err := notifier.Send(ctx, Notification{Title: "Deploy", Message: "Continue?", Level: "info", Actions: []string{"Yes"}})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	fake.emit(t, "NotificationClosed", uint32(1), uint32(2))
+
+	select {
+	case response := <-receipt.Response:
+		if response.Action != "" || response.CloseReason != "dismissed" {
+			t.Errorf("Expected dismissed close without action, got %+v", response)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for close response")
+	}
+}
+
+func TestDBusNotifier_ResponseCancelled(t *testing.T) {
+	address := startTestBus(t)
+	registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	receipt, err := notifier.Send(ctx, Notification{Title: "Deploy", Message: "Continue?", Level: "info", Actions: []string{"Yes"}})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	cancel()
+
+	select {
+	case response, ok := <-receipt.Response:
+		if ok {
+			t.Errorf("Expected response channel to close without a value, got %+v", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Response channel was not closed after cancellation")
+	}
+}
+
+func TestDBusNotifier_NoActionsNoResponse(t *testing.T) {
+	address := startTestBus(t)
+	registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	receipt, err := notifier.Send(context.Background(), Notification{Title: "Done", Message: "Finished", Level: "info"})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if receipt.Response != nil {
+		t.Error("Expected no response channel without actions")
+	}
+}
+
+func TestUrgencyByte(t *testing.T) {
+	tests := []struct {
+		urgency  string
+		expected byte
+	}{
+		{"low", 0},
+		{"normal", 1},
+		{"critical", 2},
+		{"", 1},
+		{"unknown", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urgency, func(t *testing.T) {
+			if got := urgencyByte(tt.urgency); got != tt.expected {
+				t.Errorf("urgencyByte(%q) = %d, expected %d", tt.urgency, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpireTimeoutMillis(t *testing.T) {
+	tests := []struct {
+		timeout  int
+		expected int32
+	}{
+		{0, -1},
+		{-1, 0},
+		{3000, 3000},
+	}
+
+	for _, tt := range tests {
+		if got := expireTimeoutMillis(tt.timeout); got != tt.expected {
+			t.Errorf("expireTimeoutMillis(%d) = %d, expected %d", tt.timeout, got, tt.expected)
+		}
+	}
+}
+
+func TestDBusNotifier_UpdateInPlace(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	ctx := context.Background()
+
+	if _, err := notifier.Update(ctx, "build", Notification{Title: "Build", Message: "step 1/2", Level: "info"}, 50); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	first := fake.lastCall(t)
+	if first.ReplacesID != 0 {
+		t.Errorf("Expected the first update to create a notification, got replaces_id %d", first.ReplacesID)
+	}
+	if value, ok := first.Hints["value"].Value().(int32); !ok || value != 50 {
+		t.Errorf("Expected value hint 50, got %v", first.Hints["value"])
+	}
+
+	if _, err := notifier.Update(ctx, "build", Notification{Title: "Build", Message: "step 2/2", Level: "info"}, -1); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	second := fake.lastCall(t)
+	if second.ReplacesID != 1 {
+		t.Errorf("Expected the second update to replace notification 1, got %d", second.ReplacesID)
+	}
+	if _, ok := second.Hints["value"]; ok {
+		t.Error("Expected no value hint without a percentage")
+	}
+
+	if err := notifier.Close(ctx, "build"); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	fake.mu.Lock()
+	closed := fake.closed
+	fake.mu.Unlock()
+	if len(closed) != 1 || closed[0] != 1 {
+		t.Errorf("Expected notification 1 to be closed, got %v", closed)
+	}
+
+	if err := notifier.Close(ctx, "build"); err == nil {
+		t.Error("Expected error when closing an unknown notification")
+	}
+}
+
+func TestNewDesktopNotifier_SharesSessionBus(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+	t.Cleanup(func() {
+		sessionBus.mu.Lock()
+		defer sessionBus.mu.Unlock()
+		if sessionBus.conn != nil {
+			sessionBus.conn.Close()
+		}
+		sessionBus.conn, sessionBus.ids = nil, nil
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Notification.Backend = config.BackendDBus
+	ctx := context.Background()
+
+	first, err := newDesktopNotifier(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+	if _, err := Update(ctx, first, "build", Notification{Title: "Build", Message: "step 1/2", Level: "info"}, 50); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	// A notifier created on reload reuses the connection and keeps replacing
+	second, err := newDesktopNotifier(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+	if first.(*SoundNotifier).Unwrap().(*DBusNotifier).conn != second.(*SoundNotifier).Unwrap().(*DBusNotifier).conn {
+		t.Error("Expected the session bus connection to be shared")
+	}
+	if _, err := Update(ctx, second, "build", Notification{Title: "Build", Message: "step 2/2", Level: "info"}, 100); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if call := fake.lastCall(t); call.ReplacesID != 1 {
+		t.Errorf("Expected the update after a reload to replace notification 1, got %d", call.ReplacesID)
+	}
+}
+
+func TestDBusNotifier_CloseSentNotification(t *testing.T) {
+	address := startTestBus(t)
+	fake := registerFakeServer(t, address)
+	conn := connectTestBus(t, address)
+
+	notifier := NewDBusNotifier(config.DefaultConfig(), conn)
+	ctx := context.Background()
+
+	receipt, err := notifier.Send(ctx, Notification{Title: "Approval", Message: "Deploy?", Level: "warning"})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if receipt.ID != "1" {
+		t.Fatalf("Expected the receipt to carry the daemon id 1, got %q", receipt.ID)
+	}
+
+	if err := notifier.Close(ctx, receipt.ID); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	fake.mu.Lock()
+	closed := fake.closed
+	fake.mu.Unlock()
+	if len(closed) != 1 || closed[0] != 1 {
+		t.Errorf("Expected notification 1 to be closed, got %v", closed)
+	}
+
+	if err := notifier.Close(ctx, "not-an-id"); err == nil {
+		t.Error("Expected error for an unknown id")
+	}
+}
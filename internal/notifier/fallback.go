@@ -0,0 +1,170 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// fallbackLink is one path of the fallback chain
+type fallbackLink struct {
+	namedNotifier
+	// unavailable explains why the path is skipped; empty means it is tried
+	unavailable string
+}
+
+// FallbackNotifier tries each path of the configured chain in order until one
+// delivers the notification, so that headless machines and SSH sessions still
+// get notified through the terminal or a log file
+type FallbackNotifier struct {
+	config *config.Config
+	links  []fallbackLink
+}
+
+// NewFallbackNotifier creates the paths of the configured fallback chain
+func NewFallbackNotifier(cfg *config.Config) (*FallbackNotifier, error) {
+	n := &FallbackNotifier{config: cfg}
+	for _, path := range cfg.Notification.Fallback {
+		link := fallbackLink{namedNotifier: namedNotifier{name: path}}
+		switch path {
+		case config.BackendTypeDesktop:
+			if headless() {
+				link.unavailable = "no display"
+				break
+			}
+			desktop, err := newDesktopNotifier(cfg)
+			if err != nil {
+				// Later paths can still deliver
+				link.unavailable = err.Error()
+				break
+			}
+			link.Notifier = desktop
+		case config.BackendTypeTerminal:
+			link.Notifier = NewTerminalNotifier(cfg, cfg.Notification.Terminal)
+		case config.BackendTypeFile:
+			if cfg.Notification.LogFile.Enabled {
+				// The log file sink records every notification already
+				link.Notifier = loggedNotifier{}
+				break
+			}
+			link.Notifier = NewFileNotifier(cfg, cfg.Notification.LogFile.FileConfig, nil)
+		default:
+			return nil, fmt.Errorf("unknown fallback path: %s", path)
+		}
+
+		if link.unavailable != "" && cfg.Notification.Verbose {
+			log.Printf("[FallbackNotifier] Skipping %s: %s", link.name, link.unavailable)
+		}
+		n.links = append(n.links, link)
+	}
+	return n, nil
+}
+
+// headless reports whether there is no desktop session to show notifications on.
+// macOS and Windows always have one
+func headless() bool {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		return false
+	}
+	return os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" &&
+		os.Getenv("DBUS_SESSION_BUS_ADDRESS") == ""
+}
+
+// Send tries each path until one delivers the notification. When an earlier
+// path did not deliver, the receipt lists what happened on each path
+func (n *FallbackNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	return n.fallThrough(func(path Notifier) (Receipt, error) {
+		return path.Send(ctx, notification)
+	})
+}
+
+// Update tries each path until one updates the notification
+func (n *FallbackNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	return n.fallThrough(func(path Notifier) (Receipt, error) {
+		return Update(ctx, path, id, notification, percent)
+	})
+}
+
+// Close tries each path until one closes the notification
+func (n *FallbackNotifier) Close(ctx context.Context, id string) error {
+	var errs []error
+	for _, link := range n.links {
+		if link.unavailable != "" {
+			continue
+		}
+		err := Close(ctx, link.Notifier, id)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrNotSupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+		}
+	}
+	if len(errs) == 0 {
+		return ErrNotSupported
+	}
+	return errors.Join(errs...)
+}
+
+// fallThrough calls send for each path until one succeeds
+func (n *FallbackNotifier) fallThrough(send func(path Notifier) (Receipt, error)) (Receipt, error) {
+	var deliveries []Delivery
+	var errs []error
+	for _, link := range n.links {
+		if link.unavailable != "" {
+			deliveries = append(deliveries, Delivery{Backend: link.name, Status: "skipped (" + link.unavailable + ")"})
+			continue
+		}
+
+		receipt, err := send(link.Notifier)
+		if err != nil {
+			if n.config.Notification.Verbose {
+				log.Printf("[FallbackNotifier] %s failed, trying the next path: %v", link.name, err)
+			}
+			deliveries = append(deliveries, Delivery{Backend: link.name, Err: err})
+			errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+			continue
+		}
+
+		if len(deliveries) > 0 {
+			if n.config.Notification.Verbose {
+				log.Printf("[FallbackNotifier] Delivered through %s", link.name)
+			}
+			receipt.Deliveries = append(deliveries, Delivery{Backend: link.name, Status: receipt.Status})
+		}
+		return receipt, nil
+	}
+
+	if len(errs) == 0 {
+		return Receipt{Deliveries: deliveries}, errors.New("no notification path available")
+	}
+	return Receipt{Deliveries: deliveries}, errors.Join(errs...)
+}
+
+// Flush flushes every path of the chain
+func (n *FallbackNotifier) Flush(ctx context.Context) error {
+	var errs []error
+	for _, link := range n.links {
+		if link.Notifier == nil {
+			continue
+		}
+		if err := Flush(ctx, link.Notifier); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loggedNotifier stands in for the file path when the log file sink already
+// records every notification
+type loggedNotifier struct{}
+
+// Send reports the notification as logged
+func (loggedNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	return Receipt{Status: StatusLogged}, nil
+}
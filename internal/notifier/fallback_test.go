@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// newTestFallbackNotifier chains the given paths
+func newTestFallbackNotifier(links ...fallbackLink) *FallbackNotifier {
+	return &FallbackNotifier{config: config.DefaultConfig(), links: links}
+}
+
+func TestFallbackNotifier_FirstPathDelivers(t *testing.T) {
+	desktop := &stubNotifier{}
+	terminal := &stubNotifier{}
+	n := newTestFallbackNotifier(
+		fallbackLink{namedNotifier: namedNotifier{"desktop", desktop}},
+		fallbackLink{namedNotifier: namedNotifier{"terminal", terminal}},
+	)
+
+	receipt, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if desktop.calls != 1 || terminal.calls != 0 {
+		t.Errorf("Expected only the desktop to be used, got %d desktop and %d terminal calls", desktop.calls, terminal.calls)
+	}
+	if len(receipt.Deliveries) != 0 {
+		t.Errorf("Expected no deliveries to be reported without falling back, got %v", receipt.Deliveries)
+	}
+}
+
+func TestFallbackNotifier_FallsThrough(t *testing.T) {
+	terminal := &stubNotifier{err: errors.New("no controlling terminal")}
+	n := newTestFallbackNotifier(
+		fallbackLink{namedNotifier: namedNotifier{name: "desktop"}, unavailable: "no display"},
+		fallbackLink{namedNotifier: namedNotifier{"terminal", terminal}},
+		fallbackLink{namedNotifier: namedNotifier{"file", loggedNotifier{}}},
+	)
+
+	receipt, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if receipt.Status != StatusLogged {
+		t.Errorf("Expected status %q, got %q", StatusLogged, receipt.Status)
+	}
+
+	var summary []string
+	for _, delivery := range receipt.Deliveries {
+		summary = append(summary, delivery.String())
+	}
+	expected := "desktop: skipped (no display), terminal: failed (no controlling terminal), file: logged"
+	if got := strings.Join(summary, ", "); got != expected {
+		t.Errorf("Expected deliveries %q, got %q", expected, got)
+	}
+}
+
+func TestFallbackNotifier_AllPathsFail(t *testing.T) {
+	n := newTestFallbackNotifier(
+		fallbackLink{namedNotifier: namedNotifier{"desktop", &stubNotifier{err: errors.New("no notification daemon")}}},
+		fallbackLink{namedNotifier: namedNotifier{"terminal", &stubNotifier{err: errors.New("no controlling terminal")}}},
+	)
+
+	_, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"})
+	if err == nil {
+		t.Fatal("Expected error when no path delivered")
+	}
+	if !strings.Contains(err.Error(), "desktop: no notification daemon") || !strings.Contains(err.Error(), "terminal: no controlling terminal") {
+		t.Errorf("Expected both errors, got: %v", err)
+	}
+}
+
+func TestNewNotifier_HeadlessFallback(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+
+	cfg := config.DefaultConfig()
+	cfg.Notification.LogFile.Path = filepath.Join(t.TempDir(), "notifications.jsonl")
+
+	notifier, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+	fallback, ok := notifier.(*FallbackNotifier)
+	if !ok {
+		t.Fatalf("Expected *FallbackNotifier, got %T", notifier)
+	}
+	if len(fallback.links) != 3 {
+		t.Fatalf("Expected desktop, terminal and file paths, got %d", len(fallback.links))
+	}
+	if headless() && fallback.links[0].unavailable != "no display" {
+		t.Errorf("Expected the desktop to be skipped without a display, got %q", fallback.links[0].unavailable)
+	}
+	// Notifications that fall through to the file are not heard, so stay silent
+	if _, ok := fallback.links[2].Notifier.(*FileNotifier); !ok {
+		t.Errorf("Expected the file path to use a FileNotifier, got %T", fallback.links[2].Notifier)
+	}
+}
@@ -0,0 +1,236 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// backendFactories create the notifier for each backend type
+var backendFactories = map[string]func(cfg *config.Config, backend config.BackendConfig) (Notifier, error){
+	config.BackendTypeDesktop: func(cfg *config.Config, backend config.BackendConfig) (Notifier, error) {
+		return newDesktopNotifier(cfg)
+	},
+	config.BackendTypeWebhook: func(cfg *config.Config, backend config.BackendConfig) (Notifier, error) {
+		return NewWebhookNotifier(cfg, backend.Webhook), nil
+	},
+	config.BackendTypeCommand: func(cfg *config.Config, backend config.BackendConfig) (Notifier, error) {
+		return NewCommandNotifier(cfg, backend.Command)
+	},
+	config.BackendTypeFile: func(cfg *config.Config, backend config.BackendConfig) (Notifier, error) {
+		return NewFileNotifier(cfg, backend.File, nil), nil
+	},
+	config.BackendTypeTerminal: func(cfg *config.Config, backend config.BackendConfig) (Notifier, error) {
+		return NewTerminalNotifier(cfg, backend.Terminal), nil
+	},
+}
+
+// namedNotifier is a configured backend
+type namedNotifier struct {
+	name string
+	Notifier
+}
+
+// fanoutWait bounds how long a send waits for its backends
+const fanoutWait = 5 * time.Second
+
+// StatusSending is reported for backends still delivering when the send stops
+// waiting for them; they finish in the background
+const StatusSending = "queued, still sending"
+
+// FanoutNotifier delivers each notification to the backends selected by the
+// configured routes, concurrently, so a failing backend does not hold up the others
+type FanoutNotifier struct {
+	config   *config.Config
+	backends []namedNotifier
+	wait     time.Duration
+}
+
+// NewFanoutNotifier creates the notifiers for the configured backends
+func NewFanoutNotifier(cfg *config.Config) (*FanoutNotifier, error) {
+	n := &FanoutNotifier{config: cfg, wait: fanoutWait}
+	for _, backend := range cfg.Notification.Backends {
+		factory, ok := backendFactories[backend.Type]
+		if !ok {
+			return nil, fmt.Errorf("backend %s: unknown type: %s", backend.Name, backend.Type)
+		}
+		notifier, err := factory(cfg, backend)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backend.Name, err)
+		}
+		n.backends = append(n.backends, namedNotifier{name: backend.Name, Notifier: notifier})
+	}
+	return n, nil
+}
+
+// Send delivers the notification to its backends and reports each outcome.
+// It only fails if no backend delivered the notification
+func (n *FanoutNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	return n.fanout(ctx, notification, func(ctx context.Context, backend Notifier) (Receipt, error) {
+		return backend.Send(ctx, notification)
+	})
+}
+
+// Update updates the notification in place on the backends that support it
+// and sends it again on the others
+func (n *FanoutNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	return n.fanout(ctx, notification, func(ctx context.Context, backend Notifier) (Receipt, error) {
+		return Update(ctx, backend, id, notification, percent)
+	})
+}
+
+// Close closes the notification on every backend that supports it
+func (n *FanoutNotifier) Close(ctx context.Context, id string) error {
+	var errs []error
+	supported := false
+	for _, backend := range n.backends {
+		err := Close(ctx, backend.Notifier, id)
+		if errors.Is(err, ErrNotSupported) {
+			continue
+		}
+		supported = true
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.name, err))
+		}
+	}
+	if !supported {
+		return ErrNotSupported
+	}
+	return errors.Join(errs...)
+}
+
+// fanout calls send for each backend routed for the notification, concurrently.
+// Backends still sending after the wait are left to finish in the background,
+// so a slow webhook or command does not hold up the caller
+func (n *FanoutNotifier) fanout(ctx context.Context, notification Notification, send func(ctx context.Context, backend Notifier) (Receipt, error)) (Receipt, error) {
+	targets := n.route(notification)
+	if n.config.Notification.Verbose {
+		names := make([]string, len(targets))
+		for i, target := range targets {
+			names[i] = target.name
+		}
+		log.Printf("[FanoutNotifier] Routing notification to %v - Title: %s, Level: %s, Workspace: %s",
+			names, notification.Title, notification.Level, notification.Workspace)
+	}
+
+	type outcome struct {
+		index   int
+		receipt Receipt
+		err     error
+	}
+	outcomes := make(chan outcome, len(targets))
+	for i, target := range targets {
+		go func() {
+			// Backends outlive the caller's context while sending, and only
+			// stop listening for actions once both are done
+			sendCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+			receipt, err := send(sendCtx, target.Notifier)
+			context.AfterFunc(ctx, cancel)
+			outcomes <- outcome{index: i, receipt: receipt, err: err}
+		}()
+	}
+
+	receipts := make([]Receipt, len(targets))
+	deliveries := make([]Delivery, len(targets))
+	for i, target := range targets {
+		deliveries[i] = Delivery{Backend: target.name, Status: StatusSending}
+	}
+	timer := time.NewTimer(n.wait)
+	defer timer.Stop()
+	pending := len(targets)
+wait:
+	for pending > 0 {
+		select {
+		case o := <-outcomes:
+			receipts[o.index] = o.receipt
+			deliveries[o.index] = Delivery{Backend: targets[o.index].name, Status: o.receipt.Status, Err: o.err}
+			pending--
+		case <-timer.C:
+			break wait
+		}
+	}
+	if pending > 0 {
+		go func() {
+			for range pending {
+				o := <-outcomes
+				delivery := Delivery{Backend: targets[o.index].name, Status: o.receipt.Status, Err: o.err}
+				if o.err != nil || n.config.Notification.Verbose {
+					log.Printf("[FanoutNotifier] Finished in the background - %s", delivery)
+				}
+			}
+		}()
+	}
+
+	result := Receipt{Deliveries: deliveries}
+	var errs []error
+	delivered := false
+	for i, delivery := range deliveries {
+		if delivery.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", delivery.Backend, delivery.Err))
+			if n.config.Notification.Verbose {
+				log.Printf("[FanoutNotifier] %s", delivery)
+			}
+			continue
+		}
+		if delivery.Status == "" {
+			delivered = true
+		}
+		// The first backend able to report the user's choice answers for all
+		if result.Response == nil {
+			result.Response = receipts[i].Response
+		}
+		// Closing goes to every backend, so any of their ids will do
+		if result.ID == "" {
+			result.ID = receipts[i].ID
+		}
+	}
+
+	if !delivered {
+		// Backends still sending may deliver it yet
+		if pending > 0 {
+			result.Status = StatusSending
+			return result, nil
+		}
+		if len(errs) > 0 {
+			return result, errors.Join(errs...)
+		}
+		if len(deliveries) > 0 {
+			result.Status = deliveries[0].Status
+		}
+	}
+	return result, nil
+}
+
+// route returns the backends of the first route matching the notification,
+// or every backend when no route matches
+func (n *FanoutNotifier) route(notification Notification) []namedNotifier {
+	for _, route := range n.config.Notification.Routes {
+		if !route.Matches(notification.Level, notification.Workspace) {
+			continue
+		}
+		var targets []namedNotifier
+		for _, backend := range n.backends {
+			if slices.Contains(route.Backends, backend.name) {
+				targets = append(targets, backend)
+			}
+		}
+		return targets
+	}
+	return n.backends
+}
+
+// Flush delivers what the backends hold back
+func (n *FanoutNotifier) Flush(ctx context.Context) error {
+	var errs []error
+	for _, backend := range n.backends {
+		if err := Flush(ctx, backend.Notifier); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
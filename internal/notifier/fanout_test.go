@@ -0,0 +1,255 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// newTestFanout routes to stub backends named desktop and file
+func newTestFanout(routes []config.Route) (*FanoutNotifier, *stubNotifier, *stubNotifier) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Routes = routes
+	desktop, file := &stubNotifier{}, &stubNotifier{}
+	return &FanoutNotifier{
+		config: cfg,
+		wait:   fanoutWait,
+		backends: []namedNotifier{
+			{name: "desktop", Notifier: desktop},
+			{name: "file", Notifier: file},
+		},
+	}, desktop, file
+}
+
+func TestFanoutNotifier_Routes(t *testing.T) {
+	fanout, desktop, file := newTestFanout([]config.Route{
+		{Levels: []string{"error"}, Backends: []string{"desktop", "file"}},
+		{Workspaces: []string{"ci/*"}, Backends: []string{"file"}},
+		{Backends: []string{"desktop"}},
+	})
+
+	tests := []struct {
+		notification   Notification
+		desktop, file  int
+		deliveriesText string
+	}{
+		{Notification{Title: "Deploy", Level: "error", Workspace: "projects/foo"}, 1, 1, "desktop: sent, file: sent"},
+		{Notification{Title: "Build", Level: "info", Workspace: "ci/runner"}, 1, 2, "file: sent"},
+		{Notification{Title: "Done", Level: "info", Workspace: "projects/foo"}, 2, 2, "desktop: sent"},
+	}
+
+	for _, tt := range tests {
+		receipt, err := fanout.Send(context.Background(), tt.notification)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.notification.Title, err)
+		}
+		if desktop.calls != tt.desktop || file.calls != tt.file {
+			t.Errorf("%s: expected desktop/file calls %d/%d, got %d/%d",
+				tt.notification.Title, tt.desktop, tt.file, desktop.calls, file.calls)
+		}
+		var outcomes []string
+		for _, delivery := range receipt.Deliveries {
+			outcomes = append(outcomes, delivery.String())
+		}
+		if got := strings.Join(outcomes, ", "); got != tt.deliveriesText {
+			t.Errorf("%s: expected deliveries %q, got %q", tt.notification.Title, tt.deliveriesText, got)
+		}
+	}
+}
+
+func TestFanoutNotifier_NoMatchingRoute(t *testing.T) {
+	fanout, desktop, file := newTestFanout([]config.Route{
+		{Levels: []string{"error"}, Backends: []string{"file"}},
+	})
+
+	if _, err := fanout.Send(context.Background(), Notification{Title: "Done", Level: "info"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if desktop.calls != 1 || file.calls != 1 {
+		t.Errorf("Expected unrouted notifications to reach every backend, got %d/%d", desktop.calls, file.calls)
+	}
+}
+
+func TestFanoutNotifier_PartialFailure(t *testing.T) {
+	fanout, desktop, file := newTestFanout(nil)
+	desktop.err = errors.New("no notification daemon")
+
+	receipt, err := fanout.Send(context.Background(), Notification{Title: "Build", Level: "info"})
+	if err != nil {
+		t.Fatalf("Expected success when one backend delivered, got: %v", err)
+	}
+	if file.calls != 1 {
+		t.Error("Expected the failing backend not to block the others")
+	}
+	if len(receipt.Deliveries) != 2 || receipt.Deliveries[0].Err == nil || receipt.Deliveries[1].Err != nil {
+		t.Errorf("Expected the desktop failure to be reported, got %+v", receipt.Deliveries)
+	}
+	if got := receipt.Deliveries[0].String(); got != "desktop: failed (no notification daemon)" {
+		t.Errorf("Unexpected delivery description: %q", got)
+	}
+}
+
+func TestFanoutNotifier_AllFail(t *testing.T) {
+	fanout, desktop, file := newTestFanout(nil)
+	desktop.err = errors.New("no notification daemon")
+	file.err = errors.New("disk full")
+
+	_, err := fanout.Send(context.Background(), Notification{Title: "Build", Level: "info"})
+	if err == nil {
+		t.Fatal("Expected error when no backend delivered")
+	}
+	if !strings.Contains(err.Error(), "desktop: no notification daemon") || !strings.Contains(err.Error(), "file: disk full") {
+		t.Errorf("Expected both backend errors, got: %v", err)
+	}
+}
+
+// slowNotifier delivers once released, failing if its context is done first
+type slowNotifier struct {
+	release chan struct{}
+	done    chan error
+}
+
+func (s *slowNotifier) Send(ctx context.Context, n Notification) (Receipt, error) {
+	select {
+	case <-s.release:
+		s.done <- nil
+		return Receipt{}, nil
+	case <-ctx.Done():
+		s.done <- ctx.Err()
+		return Receipt{}, ctx.Err()
+	}
+}
+
+func TestFanoutNotifier_SlowBackend(t *testing.T) {
+	fanout, desktop, _ := newTestFanout(nil)
+	webhook := &slowNotifier{release: make(chan struct{}), done: make(chan error, 1)}
+	fanout.backends[1] = namedNotifier{name: "webhook", Notifier: webhook}
+	fanout.wait = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	receipt, err := fanout.Send(ctx, Notification{Title: "Build", Level: "info"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if receipt.Status != "" || desktop.calls != 1 {
+		t.Errorf("Expected the desktop to deliver without waiting for the webhook, got status %q", receipt.Status)
+	}
+	if got := receipt.Deliveries[1].String(); got != "webhook: "+StatusSending {
+		t.Errorf("Expected the webhook to be reported as still sending, got %q", got)
+	}
+
+	// The webhook keeps sending after the caller is done
+	cancel()
+	close(webhook.release)
+	if err := <-webhook.done; err != nil {
+		t.Errorf("Expected the webhook to finish in the background, got %v", err)
+	}
+}
+
+func TestFanoutNotifier_AllSlow(t *testing.T) {
+	webhook := &slowNotifier{release: make(chan struct{}), done: make(chan error, 1)}
+	fanout := &FanoutNotifier{
+		config:   config.DefaultConfig(),
+		wait:     50 * time.Millisecond,
+		backends: []namedNotifier{{name: "webhook", Notifier: webhook}},
+	}
+	defer close(webhook.release)
+
+	// Nothing delivered yet, but nothing failed either
+	receipt, err := fanout.Send(context.Background(), Notification{Title: "Build", Level: "info"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if receipt.Status != StatusSending {
+		t.Errorf("Expected status %q, got %q", StatusSending, receipt.Status)
+	}
+}
+
+func TestNewNotifier_Backends(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Backend = config.BackendLibrary
+	cfg.Notification.Backends = []config.BackendConfig{
+		{Name: "desktop", Type: config.BackendTypeDesktop},
+		{Name: "log", Type: config.BackendTypeFile, File: config.FileConfig{Path: filepath.Join(t.TempDir(), "notifications.jsonl")}},
+	}
+
+	notifier, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+	fanout, ok := notifier.(*FanoutNotifier)
+	if !ok {
+		t.Fatalf("Expected *FanoutNotifier, got %T", notifier)
+	}
+	if len(fanout.backends) != 2 || fanout.backends[0].name != "desktop" {
+		t.Errorf("Unexpected backends: %+v", fanout.backends)
+	}
+	desktop, ok := fanout.backends[0].Notifier.(*SoundNotifier)
+	if !ok {
+		t.Fatalf("Expected the desktop backend to play sounds, got %T", fanout.backends[0].Notifier)
+	}
+	if _, ok := desktop.Unwrap().(*LibraryNotifier); !ok {
+		t.Errorf("Expected the desktop backend to use the configured desktop notifier, got %T", desktop.Unwrap())
+	}
+	// Only the desktop plays sounds, as nobody may hear the other backends
+	if _, ok := fanout.backends[1].Notifier.(*SoundNotifier); ok {
+		t.Error("Expected the file backend to stay silent")
+	}
+}
+
+// closingNotifier is a backend that hands out ids and can close notifications
+type closingNotifier struct {
+	stubNotifier
+	closed []string
+}
+
+func (c *closingNotifier) Send(ctx context.Context, n Notification) (Receipt, error) {
+	c.stubNotifier.Send(ctx, n)
+	return Receipt{ID: "7"}, nil
+}
+
+func (c *closingNotifier) Update(ctx context.Context, id string, n Notification, percent int) (Receipt, error) {
+	return c.Send(ctx, n)
+}
+
+func (c *closingNotifier) Close(ctx context.Context, id string) error {
+	c.closed = append(c.closed, id)
+	return nil
+}
+
+func TestFanoutNotifier_Close(t *testing.T) {
+	desktop := &closingNotifier{}
+	fanout := &FanoutNotifier{
+		config: config.DefaultConfig(),
+		wait:   fanoutWait,
+		backends: []namedNotifier{
+			{name: "webhook", Notifier: &stubNotifier{}},
+			{name: "desktop", Notifier: desktop},
+		},
+	}
+
+	receipt, err := fanout.Send(context.Background(), Notification{Title: "Approval", Message: "Deploy?", Level: "warning"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if receipt.ID != "7" {
+		t.Fatalf("Expected the id of the backend able to close notifications, got %q", receipt.ID)
+	}
+
+	if err := Close(context.Background(), fanout, receipt.ID); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(desktop.closed) != 1 || desktop.closed[0] != "7" {
+		t.Errorf("Expected the desktop backend to close the notification, got %v", desktop.closed)
+	}
+
+	webhookOnly := &FanoutNotifier{config: config.DefaultConfig(), wait: fanoutWait, backends: []namedNotifier{{name: "webhook", Notifier: &stubNotifier{}}}}
+	if err := Close(context.Background(), webhookOnly, "7"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported without a backend able to close notifications, got %v", err)
+	}
+}
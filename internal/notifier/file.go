@@ -0,0 +1,267 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/filelock"
+)
+
+// File log defaults
+const (
+	defaultFileMaxSizeMB  = 10
+	defaultFileMaxBackups = 3
+)
+
+// StatusLogged is reported when the notification was only written to the log file
+const StatusLogged = "logged"
+
+// fileEntry is one line of the notification log
+type fileEntry struct {
+	Timestamp  time.Time      `json:"timestamp"`
+	Level      string         `json:"level"`
+	Title      string         `json:"title"`
+	Message    string         `json:"message"`
+	Workspace  string         `json:"workspace,omitempty"`
+	Client     string         `json:"client,omitempty"`
+	Result     string         `json:"result"`
+	Error      string         `json:"error,omitempty"`
+	Deliveries []fileDelivery `json:"deliveries,omitempty"`
+}
+
+// fileDelivery is the outcome of one backend in a log entry
+type fileDelivery struct {
+	Backend string `json:"backend"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// FileNotifier writes every notification as a JSON line to a rotating file.
+// Standalone it is a backend of its own, wrapping another notifier it records
+// what that notifier delivered
+type FileNotifier struct {
+	config *config.Config
+	next   Notifier
+	file   *rotatingFile
+	now    func() time.Time
+}
+
+// NewFileNotifier creates a notifier logging to the configured file. next may
+// be nil, in which case notifications are only logged
+func NewFileNotifier(cfg *config.Config, file config.FileConfig, next Notifier) *FileNotifier {
+	maxSize := defaultFileMaxSizeMB
+	if file.MaxSizeMB > 0 {
+		maxSize = file.MaxSizeMB
+	}
+	maxBackups := defaultFileMaxBackups
+	if file.MaxBackups > 0 {
+		maxBackups = file.MaxBackups
+	}
+
+	return &FileNotifier{
+		config: cfg,
+		next:   next,
+		file: &rotatingFile{
+			path:       file.LogFilePath(),
+			maxSize:    int64(maxSize) * 1024 * 1024,
+			maxBackups: maxBackups,
+		},
+		now: time.Now,
+	}
+}
+
+// Unwrap returns the wrapped notifier, if any
+func (n *FileNotifier) Unwrap() Notifier {
+	return n.next
+}
+
+// Path returns the log file path
+func (n *FileNotifier) Path() string {
+	return n.file.path
+}
+
+// Send delivers the notification through the wrapped notifier, if any, and logs the outcome
+func (n *FileNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	receipt := Receipt{Status: StatusLogged}
+	var err error
+	if n.next != nil {
+		receipt, err = n.next.Send(ctx, notification)
+	}
+	return n.record(notification, receipt, err)
+}
+
+// Update updates the notification through the wrapped notifier, if any, and
+// logs the outcome, with the percentage in the message
+func (n *FileNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	receipt := Receipt{Status: StatusLogged}
+	var err error
+	if n.next != nil {
+		receipt, err = Update(ctx, n.next, id, notification, percent)
+	}
+	return n.record(withPercent(notification, percent), receipt, err)
+}
+
+// Close closes the notification through the wrapped notifier, if any
+func (n *FileNotifier) Close(ctx context.Context, id string) error {
+	if n.next == nil {
+		return ErrNotSupported
+	}
+	return Close(ctx, n.next, id)
+}
+
+// record writes the notification and the outcome of delivering it to the log
+// file, returning the outcome to report
+func (n *FileNotifier) record(notification Notification, receipt Receipt, err error) (Receipt, error) {
+	entry := fileEntry{
+		Timestamp: n.now().UTC(),
+		Level:     notification.Level,
+		Title:     notification.Title,
+		Message:   notification.Message,
+		Workspace: notification.Workspace,
+		Client:    notification.Client,
+		Result:    "sent",
+	}
+	if err != nil {
+		entry.Result = "failed"
+		entry.Error = err.Error()
+	} else if receipt.Status != "" {
+		entry.Result = receipt.Status
+	}
+	for _, delivery := range receipt.Deliveries {
+		logged := fileDelivery{Backend: delivery.Backend, Status: delivery.Status}
+		if delivery.Err != nil {
+			logged.Error = delivery.Err.Error()
+		}
+		entry.Deliveries = append(entry.Deliveries, logged)
+	}
+
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr == nil {
+		marshalErr = n.file.writeLine(line)
+	}
+	if marshalErr != nil {
+		// Standalone, failing to log means the notification went nowhere
+		if n.next == nil {
+			return Receipt{}, fmt.Errorf("failed to write notification log: %w", marshalErr)
+		}
+		// As a secondary sink it must not turn a delivered notification into an error
+		if n.config.Notification.Verbose {
+			log.Printf("[FileNotifier] Failed to write notification log: %v", marshalErr)
+		}
+	} else if n.config.Notification.Verbose && n.next == nil {
+		log.Printf("[FileNotifier] Logged notification to %s - Title: %s, Level: %s",
+			n.file.path, notification.Title, notification.Level)
+	}
+
+	return receipt, err
+}
+
+// Flush closes the log file so everything written so far is on disk
+func (n *FileNotifier) Flush(ctx context.Context) error {
+	return n.file.close()
+}
+
+// rotatingFile appends lines to a file, moving it to path.1, path.2, ... once
+// it grows past maxSize
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// writeLine appends line and a newline, rotating first if it would not fit
+func (f *rotatingFile) writeLine(line []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.size > 0 && f.size+int64(len(line))+1 > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	written, err := f.file.Write(append(line, '\n'))
+	f.size += int64(written)
+	return err
+}
+
+// open opens the log file for appending, picking up its current size
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %w", f.path, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups, dropping the oldest, and starts a new file.
+// Other processes logging to the same file rotate it too, so the backups are
+// shifted under a lock on path.lock, and only if no one rotated it already
+func (f *rotatingFile) rotate() error {
+	lock, err := filelock.Lock(f.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	current, err := f.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", f.path, err)
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", f.path, err)
+	}
+	f.file = nil
+
+	if latest, err := os.Stat(f.path); err != nil || !os.SameFile(current, latest) {
+		// Another process moved the file away, continue with the new one
+		return f.open()
+	}
+
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", f.path, err)
+	}
+	return f.open()
+}
+
+// close closes the file, it is reopened on the next write
+func (f *rotatingFile) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,148 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// readFileEntries decodes every line of a notification log
+func readFileEntries(t *testing.T, path string) []fileEntry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []fileEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry fileEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestFileNotifier_Standalone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "notifications.jsonl")
+	n := NewFileNotifier(config.DefaultConfig(), config.FileConfig{Path: path}, nil)
+
+	receipt, err := n.Send(context.Background(), Notification{
+		Title: "Build", Message: "passed", Level: "success", Workspace: "projects/foo", Client: "claude-code",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if receipt.Status != StatusLogged {
+		t.Errorf("Expected status %q, got %q", StatusLogged, receipt.Status)
+	}
+	if err := Flush(context.Background(), n); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	entries := readFileEntries(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Title != "Build" || entry.Level != "success" || entry.Workspace != "projects/foo" ||
+		entry.Client != "claude-code" || entry.Result != StatusLogged || entry.Timestamp.IsZero() {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestFileNotifier_RecordsDeliveryResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	next := &stubNotifier{}
+	n := NewFileNotifier(config.DefaultConfig(), config.FileConfig{Path: path}, next)
+
+	if _, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	next.err = errors.New("no notification daemon")
+	if _, err := n.Send(context.Background(), Notification{Title: "Deploy", Message: "failed", Level: "error"}); err == nil {
+		t.Fatal("Expected the wrapped notifier's error to be returned")
+	}
+
+	entries := readFileEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Result != "sent" || entries[0].Error != "" {
+		t.Errorf("Expected first entry to be sent, got %+v", entries[0])
+	}
+	if entries[1].Result != "failed" || entries[1].Error != "no notification daemon" {
+		t.Errorf("Expected second entry to be failed, got %+v", entries[1])
+	}
+}
+
+func TestFileNotifier_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	n := NewFileNotifier(config.DefaultConfig(), config.FileConfig{Path: path, MaxBackups: 2}, nil)
+	// Room for one entry per file
+	n.file.maxSize = 200
+
+	for _, title := range []string{"one", "two", "three", "four"} {
+		if _, err := n.Send(context.Background(), Notification{Title: title, Message: "m", Level: "info"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	for suffix, title := range map[string]string{"": "four", ".1": "three", ".2": "two"} {
+		entries := readFileEntries(t, path+suffix)
+		if len(entries) != 1 || entries[0].Title != title {
+			t.Errorf("Expected %s%s to hold %q, got %+v", filepath.Base(path), suffix, title, entries)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept, stat error: %v", err)
+	}
+}
+
+func TestFileNotifier_RotatesSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+
+	// Each notifier stands for a server logging to the same file. Their
+	// rotations must not overwrite each other's backups
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c"} {
+		n := NewFileNotifier(config.DefaultConfig(), config.FileConfig{Path: path, MaxBackups: 300}, nil)
+		n.file.maxSize = 200
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := n.Send(context.Background(), Notification{Title: name, Message: "m", Level: "info"}); err != nil {
+					t.Errorf("Send failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	logged := 0
+	for _, file := range files {
+		if filepath.Ext(file) != ".lock" {
+			logged += len(readFileEntries(t, file))
+		}
+	}
+	if logged != 300 {
+		t.Errorf("Expected all 300 notifications in the log and its backups, got %d", logged)
+	}
+}
@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
+)
+
+// HistoryNotifier records every notification and its delivery outcome in the history store
+type HistoryNotifier struct {
+	config *config.Config
+	next   Notifier
+	store  *history.Store
+}
+
+// NewHistoryNotifier wraps next so that everything it sends is recorded in store
+func NewHistoryNotifier(cfg *config.Config, next Notifier, store *history.Store) *HistoryNotifier {
+	return &HistoryNotifier{
+		config: cfg,
+		next:   next,
+		store:  store,
+	}
+}
+
+// Unwrap returns the wrapped notifier
+func (n *HistoryNotifier) Unwrap() Notifier {
+	return n.next
+}
+
+// Send delivers the notification through the wrapped notifier and records the outcome
+func (n *HistoryNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	receipt, err := n.next.Send(ctx, notification)
+	n.record(notification, receipt, err)
+	return receipt, err
+}
+
+// Update updates the notification through the wrapped notifier and records
+// the outcome, with the percentage in the message
+func (n *HistoryNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	receipt, err := Update(ctx, n.next, id, notification, percent)
+	n.record(withPercent(notification, percent), receipt, err)
+	return receipt, err
+}
+
+// Close closes the notification through the wrapped notifier
+func (n *HistoryNotifier) Close(ctx context.Context, id string) error {
+	return Close(ctx, n.next, id)
+}
+
+// record appends the notification and the outcome of delivering it to the store
+func (n *HistoryNotifier) record(notification Notification, receipt Receipt, err error) {
+	entry := history.Entry{
+		Timestamp: time.Now(),
+		Title:     notification.Title,
+		Message:   notification.Message,
+		Level:     notification.Level,
+		Workspace: notification.Workspace,
+		Client:    notification.Client,
+		Actions:   notification.Actions,
+		Status:    history.StatusSent,
+	}
+	if err != nil {
+		entry.Status = history.StatusFailed
+		entry.Error = err.Error()
+	} else if receipt.Status != "" {
+		entry.Status = receipt.Status
+	}
+
+	// A history failure must not turn a delivered notification into an error
+	if appendErr := n.store.Append(entry); appendErr != nil && n.config.Notification.Verbose {
+		log.Printf("[HistoryNotifier] Failed to record notification: %v", appendErr)
+	}
+}
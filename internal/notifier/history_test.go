@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
+)
+
+// stubNotifier returns a fixed result and counts how often it is called
+type stubNotifier struct {
+	err   error
+	calls int
+	last  Notification
+}
+
+func (s *stubNotifier) Send(ctx context.Context, n Notification) (Receipt, error) {
+	s.calls++
+	s.last = n
+	return Receipt{}, s.err
+}
+
+func TestHistoryNotifier_RecordsOutcome(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	next := &stubNotifier{}
+	notifier := NewHistoryNotifier(config.DefaultConfig(), next, store)
+
+	sent := Notification{Title: "Build", Message: "passed", Level: "success", Workspace: "projects/a", Client: "claude-code"}
+	if _, err := notifier.Send(context.Background(), sent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next.err = errors.New("no notification daemon")
+	if _, err := notifier.Send(context.Background(), Notification{Title: "Deploy", Message: "failed", Level: "error"}); err == nil {
+		t.Error("Expected the wrapped notifier's error to be returned")
+	}
+
+	if next.calls != 2 {
+		t.Errorf("Expected 2 calls to the wrapped notifier, got %d", next.calls)
+	}
+
+	entries, err := store.List(history.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(entries))
+	}
+
+	if entries[0].Status != history.StatusSent || entries[0].Workspace != "projects/a" || entries[0].Client != "claude-code" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Status != history.StatusFailed || entries[1].Error != "no notification daemon" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestHistoryNotifier_RecordsUpdates(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	next := &closingNotifier{}
+	notifier := NewHistoryNotifier(config.DefaultConfig(), next, store)
+
+	update := Notification{Title: "Building", Message: "running tests", Level: "info"}
+	if _, err := Update(context.Background(), notifier, "7", update, 30); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The wrapped notifier updates in place, without the percentage in the message
+	if next.calls != 1 || next.last.Message != "running tests" {
+		t.Errorf("Expected the update to be made in place, got %d calls with %+v", next.calls, next.last)
+	}
+
+	entries, err := store.List(history.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "running tests (30%)" {
+		t.Errorf("Expected the update recorded with its percentage, got %+v", entries)
+	}
+}
@@ -1,20 +1,217 @@
 package notifier
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/sound"
 	"github.com/gen2brain/beeep"
 )
 
 // Notifier is the interface for sending notifications
 type Notifier interface {
-	Send(title, message, level string) error
+	Send(ctx context.Context, n Notification) (Receipt, error)
+}
+
+// Flusher is implemented by notifiers that hold notifications back and must
+// deliver them before the server shuts down
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush delivers the notifications held back by n or by any notifier it wraps,
+// innermost first so that the outer notifiers record the digests sent before
+// flushing themselves. Wrapping notifiers expose what they wrap through an
+// Unwrap() Notifier method
+func Flush(ctx context.Context, n Notifier) error {
+	var errs []error
+	for _, n := range slices.Backward(chain(n)) {
+		if flusher, ok := n.(Flusher); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// chain returns n and the notifiers it wraps, outermost first
+func chain(n Notifier) []Notifier {
+	var notifiers []Notifier
+	for n != nil {
+		notifiers = append(notifiers, n)
+		wrapper, ok := n.(interface{ Unwrap() Notifier })
+		if !ok {
+			break
+		}
+		n = wrapper.Unwrap()
+	}
+	return notifiers
+}
+
+// digestSender is implemented by notifiers that send digests of the
+// notifications they hold back
+type digestSender interface {
+	sendDigestsThrough(top Notifier)
+}
+
+// RouteDigests makes the notifiers in the chain of top send their digests
+// through top, so that the outer notifiers such as the history record them
+// like any other notification. Without it digests go to the wrapped notifier
+func RouteDigests(top Notifier) {
+	for _, n := range chain(top) {
+		if sender, ok := n.(digestSender); ok {
+			sender.sendDigestsThrough(top)
+		}
+	}
+}
+
+// digestKey marks the context of a digest sent through the whole chain
+type digestKey struct{}
+
+// sendDigest sends digest through top, or through next if digests are not
+// routed. The notifiers holding notifications back pass it on untouched, as
+// it is made of notifications they let through already
+func sendDigest(ctx context.Context, top, next Notifier, digest Notification) (Receipt, error) {
+	if top == nil {
+		return next.Send(ctx, digest)
+	}
+	return top.Send(context.WithValue(ctx, digestKey{}, true), digest)
+}
+
+// isDigest reports whether ctx is sending a digest through the whole chain
+func isDigest(ctx context.Context) bool {
+	return ctx.Value(digestKey{}) != nil
+}
+
+// ErrNotSupported is returned when no notifier in the chain can close notifications
+var ErrNotSupported = errors.New("not supported by the notification backend")
+
+// Updater is implemented by notifiers that can update a notification in place
+// and close it, such as a progress notification for a long task
+type Updater interface {
+	// Update shows notification as the notification identified by id, replacing
+	// it if it is still shown. percent is the completion hint, negative for none
+	Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error)
+	// Close removes the notification identified by id
+	Close(ctx context.Context, id string) error
+}
+
+// Update updates the notification identified by id in place if n supports it.
+// Otherwise a new notification is sent through n, with the percentage in the
+// message. Wrapping notifiers implement Updater to apply their policy to
+// updates too, passing them on with Update
+func Update(ctx context.Context, n Notifier, id string, notification Notification, percent int) (Receipt, error) {
+	if updater, ok := n.(Updater); ok {
+		return updater.Update(ctx, id, notification, percent)
+	}
+	return n.Send(ctx, withPercent(notification, percent))
+}
+
+// withPercent returns notification with the percentage, if any, appended to
+// the message, for notifiers that cannot show it otherwise
+func withPercent(notification Notification, percent int) Notification {
+	if percent >= 0 {
+		notification.Message = fmt.Sprintf("%s (%d%%)", notification.Message, percent)
+	}
+	return notification
+}
+
+// Close closes the notification identified by id through the first notifier
+// in the chain of n that supports it
+func Close(ctx context.Context, n Notifier, id string) error {
+	if updater := findUpdater(n); updater != nil {
+		return updater.Close(ctx, id)
+	}
+	return ErrNotSupported
+}
+
+// findUpdater returns the first notifier in the chain of n implementing Updater
+func findUpdater(n Notifier) Updater {
+	for n != nil {
+		if updater, ok := n.(Updater); ok {
+			return updater
+		}
+		wrapper, ok := n.(interface{ Unwrap() Notifier })
+		if !ok {
+			return nil
+		}
+		n = wrapper.Unwrap()
+	}
+	return nil
+}
+
+// Notification is a single notification to be delivered
+type Notification struct {
+	Title   string
+	Message string
+	Level   string
+	// Workspace identifies where the notification came from (see AppName)
+	Workspace string
+	// Client is the name of the MCP client that requested the notification
+	Client string
+	// Actions are labels for buttons shown on the notification, if the backend supports them
+	Actions []string
+}
+
+// Statuses reported in a Receipt when a notification was not delivered
+const (
+	StatusDuplicate   = "suppressed (duplicate)"
+	StatusRateLimited = "rate limited"
+)
+
+// Receipt describes the outcome of sending a notification
+type Receipt struct {
+	// Status explains why the notification was not delivered; empty means it was
+	Status string
+
+	// ID identifies the delivered notification so it can be closed later.
+	// It is empty when the backend cannot close notifications
+	ID string
+
+	// Response delivers the user's reaction to a notification with actions.
+	// It is nil when the notification has no actions or the backend cannot report them,
+	// and it is closed without a value if ctx is done before the user reacts
+	Response <-chan Response
+
+	// Deliveries reports the outcome of each backend when several are configured
+	Deliveries []Delivery
+}
+
+// Delivery is the outcome of sending a notification through one backend
+type Delivery struct {
+	Backend string
+	// Status explains why the backend did not deliver the notification; empty means it did
+	Status string
+	Err    error
+}
+
+// String describes the outcome, e.g. "desktop: sent" or "webhook: failed (timeout)"
+func (d Delivery) String() string {
+	switch {
+	case d.Err != nil:
+		return fmt.Sprintf("%s: failed (%v)", d.Backend, d.Err)
+	case d.Status != "":
+		return fmt.Sprintf("%s: %s", d.Backend, d.Status)
+	default:
+		return fmt.Sprintf("%s: sent", d.Backend)
+	}
+}
+
+// Response is the user's reaction to a notification with actions
+type Response struct {
+	// Action is the label of the chosen action, empty if the notification was closed instead
+	Action string
+	// CloseReason explains why the notification was closed without an action
+	CloseReason string
 }
 
 // LibraryNotifier sends notifications using the beeep library
@@ -41,35 +238,86 @@ func NewNotifier(cfg *config.Config) (Notifier, error) {
 		return &DryRunNotifier{config: cfg}, nil
 	}
 
-	// Create library-based notifier (always uses beeep library)
+	// Deliver to several backends when configured
+	if len(cfg.Notification.Backends) > 0 {
+		return NewFanoutNotifier(cfg)
+	}
+
+	// Fall back to the terminal or a log file when the desktop is unavailable
+	if len(cfg.Notification.Fallback) > 1 ||
+		(len(cfg.Notification.Fallback) == 1 && cfg.Notification.Fallback[0] != config.BackendTypeDesktop) {
+		return NewFallbackNotifier(cfg)
+	}
+
+	return newDesktopNotifier(cfg)
+}
+
+// newDesktopNotifier creates the desktop notifier selected by the backend
+// setting, playing the level sounds along with what it shows. Other backends
+// and fallback paths stay silent, as nobody may be at the speaker
+func newDesktopNotifier(cfg *config.Config) (Notifier, error) {
+	desktop, err := newDesktopBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewSoundNotifier(cfg, desktop, sound.NewPlayer()), nil
+}
+
+// newDesktopBackend creates the desktop notifier selected by the backend setting
+func newDesktopBackend(cfg *config.Config) (Notifier, error) {
+	switch cfg.Notification.Backend {
+	case config.BackendDBus:
+		notifier, err := newSessionDBusNotifier(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+		}
+		return notifier, nil
+	case config.BackendAuto:
+		// Prefer talking to the notification daemon directly on Linux so
+		// urgency and the other level settings are honored
+		if runtime.GOOS == "linux" {
+			notifier, err := newSessionDBusNotifier(cfg)
+			if err == nil {
+				return notifier, nil
+			}
+			if cfg.Notification.Verbose {
+				log.Printf("[Notifier] Session bus unavailable, falling back to beeep: %v", err)
+			}
+		}
+	}
+
+	// Create library-based notifier (uses beeep library)
 	return &LibraryNotifier{config: cfg}, nil
 }
 
 // Send sends a notification using the beeep library
-func (n *LibraryNotifier) Send(title, message, level string) error {
+func (n *LibraryNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
 	// Get icon based on level
-	icon := n.getIcon(level)
+	icon := n.getIcon(notification.Level)
 
 	// Log if verbose
 	if n.config.Notification.Verbose {
 		log.Printf("[LibraryNotifier] Sending notification - Title: %s, Message: %s, Level: %s, Icon: %s, Platform: %s",
-			title, message, level, icon, runtime.GOOS)
+			notification.Title, notification.Message, notification.Level, icon, runtime.GOOS)
+		if len(notification.Actions) > 0 {
+			log.Printf("[LibraryNotifier] Actions are not supported by beeep, ignoring: %v", notification.Actions)
+		}
 	}
 
 	// Send notification using beeep
-	err := beeep.Notify(title, message, icon)
+	err := beeep.Notify(notification.Title, notification.Message, icon)
 	if err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+		return Receipt{}, fmt.Errorf("failed to send notification: %w", err)
 	}
 
-	return nil
+	return Receipt{}, nil
 }
 
 // Send logs the notification without sending it
-func (n *DryRunNotifier) Send(title, message, level string) error {
-	log.Printf("[DRY RUN] Would send notification - Title: %s, Message: %s, Level: %s, Platform: %s",
-		title, message, level, runtime.GOOS)
-	return nil
+func (n *DryRunNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	log.Printf("[DRY RUN] Would send notification - Title: %s, Message: %s, Level: %s, Actions: %v, Platform: %s",
+		notification.Title, notification.Message, notification.Level, notification.Actions, runtime.GOOS)
+	return Receipt{}, nil
 }
 
 // getUrgency returns the urgency level for a notification level
@@ -88,6 +336,11 @@ func (n *LibraryNotifier) getIcon(level string) string {
 	return "" // default (no icon)
 }
 
+// AppName returns the workspace identifier used as the notification app name
+func AppName() string {
+	return getAppName()
+}
+
 // getAppName extracts the last 2 directories from PWD environment variable
 // Returns "mcp-poke" as default if PWD is not available or path is too short
 func getAppName() string {
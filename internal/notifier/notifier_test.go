@@ -1,23 +1,33 @@
 package notifier
 
 import (
+	"context"
+	"errors"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
 	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
 )
 
 func TestNewNotifier_LibraryMode(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.Notification.DryRun = false
+	cfg.Notification.Backend = config.BackendLibrary
+	cfg.Notification.Fallback = []string{config.BackendTypeDesktop}
 
 	notifier, err := NewNotifier(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create notifier: %v", err)
 	}
 
-	if _, ok := notifier.(*LibraryNotifier); !ok {
+	sounding, ok := notifier.(*SoundNotifier)
+	if !ok {
+		t.Fatalf("Expected the desktop notifier to play sounds, got %T", notifier)
+	}
+	if _, ok := sounding.Unwrap().(*LibraryNotifier); !ok {
 		t.Error("Expected LibraryNotifier")
 	}
 }
@@ -50,7 +60,7 @@ func TestLibraryNotifier_Send(t *testing.T) {
 
 	// Test sending notification (won't actually show on desktop during test)
 	// This test may fail in headless environments, which is expected
-	err := notifier.Send("Test Title", "Test Message", "info")
+	_, err := notifier.Send(context.Background(), Notification{Title: "Test Title", Message: "Test Message", Level: "info"})
 
 	// On Linux without X server, this might fail, which is expected in CI
 	// We just check that the method doesn't panic
@@ -119,7 +129,7 @@ func TestDryRunNotifier_Send(t *testing.T) {
 	}
 
 	// Dry run should always succeed
-	err := notifier.Send("Test Title", "Test Message", "info")
+	_, err := notifier.Send(context.Background(), Notification{Title: "Test Title", Message: "Test Message", Level: "info"})
 	if err != nil {
 		t.Errorf("DryRun should not return error, got: %v", err)
 	}
@@ -154,7 +164,7 @@ func TestLibraryNotifier_EmptyTitle(t *testing.T) {
 		config: cfg,
 	}
 
-	err := notifier.Send("", "Test Message", "info")
+	_, err := notifier.Send(context.Background(), Notification{Title: "", Message: "Test Message", Level: "info"})
 
 	// Should handle empty title gracefully
 	if err != nil {
@@ -172,7 +182,7 @@ func TestLibraryNotifier_EmptyMessage(t *testing.T) {
 		config: cfg,
 	}
 
-	err := notifier.Send("Test Title", "", "info")
+	_, err := notifier.Send(context.Background(), Notification{Title: "Test Title", Message: "", Level: "info"})
 
 	// Should handle empty message gracefully or return error
 	if err != nil {
@@ -241,3 +251,67 @@ func TestGetAppName(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdate_FallsBackToSend(t *testing.T) {
+	next := &stubNotifier{}
+	limiter := NewRateLimitNotifier(config.DefaultConfig(), next)
+
+	if _, err := Update(context.Background(), limiter, "build", Notification{Title: "Build", Message: "compiling", Level: "info"}, 40); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if next.calls != 1 || next.last.Message != "compiling (40%)" {
+		t.Errorf("Expected a new notification with the percentage, got %d calls, last %+v", next.calls, next.last)
+	}
+
+	if err := Close(context.Background(), limiter, "build"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestRouteDigests_Recorded(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Batch = config.BatchConfig{Enabled: true, Threshold: 1, Window: 60}
+	// The three notifications use up the rate limit, which the digest is not subject to
+	cfg.Notification.RateLimit = config.RateLimitConfig{Enabled: true, Default: config.RateLimit{PerMinute: 1, Burst: 3}}
+
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	logPath := filepath.Join(t.TempDir(), "notifications.jsonl")
+
+	next := &collectingNotifier{}
+	limiter := NewRateLimitNotifier(cfg, NewBatchNotifier(cfg, next))
+	top := NewFileNotifier(cfg, config.FileConfig{Path: logPath}, NewHistoryNotifier(cfg, limiter, store))
+	RouteDigests(top)
+
+	for _, title := range []string{"Build", "Lint", "Test"} {
+		sendStatus(t, top, Notification{Title: title, Message: "done", Level: "info", Workspace: "projects/foo"})
+	}
+	if err := Flush(context.Background(), top); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	delivered := next.delivered()
+	if len(delivered) != 2 || delivered[1].Title != "2 updates from projects/foo" {
+		t.Fatalf("Expected the first notification and a digest, got %+v", delivered)
+	}
+
+	// The digest is recorded along with the notifications it is made of
+	entries, err := store.List(history.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 4 || entries[3].Title != delivered[1].Title || entries[3].Status != history.StatusSent {
+		t.Errorf("Expected the digest recorded as sent, got %+v", entries)
+	}
+	logged := readFileEntries(t, logPath)
+	if len(logged) != 4 || logged[3].Title != delivered[1].Title {
+		t.Errorf("Expected the digest in the notification log, got %+v", logged)
+	}
+
+	// The log file is flushed after the digest is written to it
+	if top.file.file != nil {
+		t.Error("Expected the log file to be closed by Flush")
+	}
+}
@@ -0,0 +1,160 @@
+package notifier
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// RateLimitNotifier applies per-level token bucket rate limits and suppresses
+// duplicate notifications before passing them on
+type RateLimitNotifier struct {
+	config *config.Config
+	next   Notifier
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	recent  map[duplicateKey]time.Time
+	now     func() time.Time
+}
+
+// duplicateKey identifies notifications considered identical
+type duplicateKey struct {
+	title, message, level string
+}
+
+// tokenBucket holds the remaining tokens of a level and when they were last refilled
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitNotifier wraps next with rate limiting and duplicate suppression
+func NewRateLimitNotifier(cfg *config.Config, next Notifier) *RateLimitNotifier {
+	return &RateLimitNotifier{
+		config:  cfg,
+		next:    next,
+		buckets: make(map[string]*tokenBucket),
+		recent:  make(map[duplicateKey]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Unwrap returns the wrapped notifier
+func (n *RateLimitNotifier) Unwrap() Notifier {
+	return n.next
+}
+
+// Send passes the notification on unless it is a duplicate or its level is over
+// the rate limit. Digests are passed on untouched
+func (n *RateLimitNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	// Digests are not counted, the notifications in them were already
+	if isDigest(ctx) {
+		return n.next.Send(ctx, notification)
+	}
+	return n.limit(notification, func() (Receipt, error) {
+		return n.next.Send(ctx, notification)
+	})
+}
+
+// Update passes the update on unless it repeats the previous one or its level
+// is over the rate limit, like Send
+func (n *RateLimitNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	return n.limit(withPercent(notification, percent), func() (Receipt, error) {
+		return Update(ctx, n.next, id, notification, percent)
+	})
+}
+
+// Close closes the notification through the wrapped notifier
+func (n *RateLimitNotifier) Close(ctx context.Context, id string) error {
+	return Close(ctx, n.next, id)
+}
+
+// limit calls deliver unless notification is a duplicate or its level is over the rate limit
+func (n *RateLimitNotifier) limit(notification Notification, deliver func() (Receipt, error)) (Receipt, error) {
+	key := duplicateKey{notification.Title, notification.Message, notification.Level}
+
+	n.mu.Lock()
+	status := n.admit(key)
+	n.mu.Unlock()
+
+	if status != "" {
+		if n.config.Notification.Verbose {
+			log.Printf("[RateLimitNotifier] Notification %s - Title: %s, Level: %s", status, notification.Title, notification.Level)
+		}
+		return Receipt{Status: status}, nil
+	}
+
+	receipt, err := deliver()
+	if err != nil {
+		// A failed delivery should not suppress the retry
+		n.mu.Lock()
+		delete(n.recent, key)
+		n.mu.Unlock()
+	}
+	return receipt, err
+}
+
+// admit decides whether a notification may be sent, returning the status if not.
+// Must be called with n.mu held
+func (n *RateLimitNotifier) admit(key duplicateKey) string {
+	now := n.now()
+
+	window := time.Duration(n.config.Notification.RateLimit.DuplicateWindow) * time.Second
+	if window > 0 {
+		for k, sent := range n.recent {
+			if now.Sub(sent) >= window {
+				delete(n.recent, k)
+			}
+		}
+		if _, ok := n.recent[key]; ok {
+			return StatusDuplicate
+		}
+	}
+
+	if !n.take(key.level, now) {
+		return StatusRateLimited
+	}
+
+	if window > 0 {
+		n.recent[key] = now
+	}
+	return ""
+}
+
+// take consumes a token from the level's bucket, reporting whether one was available.
+// Must be called with n.mu held
+func (n *RateLimitNotifier) take(level string, now time.Time) bool {
+	limit := n.config.RateLimitFor(level)
+	if limit.PerMinute <= 0 {
+		return true
+	}
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	bucket, ok := n.buckets[level]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		n.buckets[level] = bucket
+	}
+
+	// Refill for the time elapsed since the last notification
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens += elapsed * float64(limit.PerMinute) / 60
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
@@ -0,0 +1,173 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// newTestRateLimiter returns a rate limiter with a controllable clock
+func newTestRateLimiter(cfg *config.Config, next Notifier) (*RateLimitNotifier, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimitNotifier(cfg, next)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func sendStatus(t *testing.T, n Notifier, notification Notification) string {
+	t.Helper()
+	receipt, err := n.Send(context.Background(), notification)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return receipt.Status
+}
+
+func TestRateLimitNotifier_Duplicates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.RateLimit.DuplicateWindow = 30
+	next := &stubNotifier{}
+	limiter, now := newTestRateLimiter(cfg, next)
+
+	build := Notification{Title: "Build", Message: "passed", Level: "success"}
+
+	if status := sendStatus(t, limiter, build); status != "" {
+		t.Errorf("Expected first notification to be delivered, got %q", status)
+	}
+	if status := sendStatus(t, limiter, build); status != StatusDuplicate {
+		t.Errorf("Expected duplicate to be suppressed, got %q", status)
+	}
+
+	// A different level is not a duplicate
+	if status := sendStatus(t, limiter, Notification{Title: "Build", Message: "passed", Level: "info"}); status != "" {
+		t.Errorf("Expected different level to be delivered, got %q", status)
+	}
+
+	// After the window the same notification goes through again
+	*now = now.Add(31 * time.Second)
+	if status := sendStatus(t, limiter, build); status != "" {
+		t.Errorf("Expected notification after the window to be delivered, got %q", status)
+	}
+
+	if next.calls != 3 {
+		t.Errorf("Expected 3 deliveries, got %d", next.calls)
+	}
+}
+
+func TestRateLimitNotifier_DuplicateWindowDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.RateLimit.DuplicateWindow = 0
+	next := &stubNotifier{}
+	limiter, _ := newTestRateLimiter(cfg, next)
+
+	build := Notification{Title: "Build", Message: "passed", Level: "success"}
+	sendStatus(t, limiter, build)
+	if status := sendStatus(t, limiter, build); status != "" {
+		t.Errorf("Expected duplicates to be delivered when the window is disabled, got %q", status)
+	}
+}
+
+func TestRateLimitNotifier_TokenBucket(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.RateLimit.DuplicateWindow = 0
+	cfg.Notification.RateLimit.Default = config.RateLimit{PerMinute: 6, Burst: 3}
+	next := &stubNotifier{}
+	limiter, now := newTestRateLimiter(cfg, next)
+
+	info := Notification{Title: "Step", Message: "progress", Level: "info"}
+
+	for i := 0; i < 3; i++ {
+		if status := sendStatus(t, limiter, info); status != "" {
+			t.Fatalf("Expected burst notification %d to be delivered, got %q", i+1, status)
+		}
+	}
+	if status := sendStatus(t, limiter, info); status != StatusRateLimited {
+		t.Errorf("Expected notification over the burst to be rate limited, got %q", status)
+	}
+
+	// Other levels have their own bucket
+	if status := sendStatus(t, limiter, Notification{Title: "Oops", Message: "failed", Level: "error"}); status != "" {
+		t.Errorf("Expected error level to have its own bucket, got %q", status)
+	}
+
+	// 6 per minute refills one token every 10 seconds
+	*now = now.Add(10 * time.Second)
+	if status := sendStatus(t, limiter, info); status != "" {
+		t.Errorf("Expected refilled token to allow delivery, got %q", status)
+	}
+	if status := sendStatus(t, limiter, info); status != StatusRateLimited {
+		t.Errorf("Expected bucket to be empty again, got %q", status)
+	}
+}
+
+func TestRateLimitNotifier_PerLevelLimit(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.RateLimit.DuplicateWindow = 0
+	cfg.Notification.RateLimit.Default = config.RateLimit{PerMinute: 60, Burst: 10}
+	errorLevel := cfg.Notification.Levels["error"]
+	errorLevel.RateLimit = config.RateLimit{PerMinute: 1, Burst: 1}
+	cfg.Notification.Levels["error"] = errorLevel
+	limiter, _ := newTestRateLimiter(cfg, &stubNotifier{})
+
+	failure := Notification{Title: "Oops", Message: "failed", Level: "error"}
+	sendStatus(t, limiter, failure)
+	if status := sendStatus(t, limiter, failure); status != StatusRateLimited {
+		t.Errorf("Expected per-level limit to apply, got %q", status)
+	}
+
+	// Unlimited when the default has no rate
+	cfg.Notification.RateLimit.Default = config.RateLimit{}
+	for i := 0; i < 20; i++ {
+		if status := sendStatus(t, limiter, Notification{Title: "Step", Message: "progress", Level: "info"}); status != "" {
+			t.Fatalf("Expected no rate limit without a configured rate, got %q", status)
+		}
+	}
+}
+
+func TestRateLimitNotifier_FailedDeliveryNotSuppressed(t *testing.T) {
+	cfg := config.DefaultConfig()
+	next := &stubNotifier{err: errors.New("no notification daemon")}
+	limiter, _ := newTestRateLimiter(cfg, next)
+
+	build := Notification{Title: "Build", Message: "passed", Level: "success"}
+	if _, err := limiter.Send(context.Background(), build); err == nil {
+		t.Fatal("Expected the wrapped notifier's error")
+	}
+
+	next.err = nil
+	if status := sendStatus(t, limiter, build); status != "" {
+		t.Errorf("Expected retry after a failure to be delivered, got %q", status)
+	}
+}
+
+func TestRateLimitNotifier_Updates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.RateLimit.DuplicateWindow = 30
+	next := &closingNotifier{}
+	limiter, _ := newTestRateLimiter(cfg, next)
+
+	update := Notification{Title: "Building", Message: "running tests", Level: "info"}
+	for _, step := range []struct {
+		percent int
+		status  string
+	}{
+		{30, ""},
+		{30, StatusDuplicate},
+		{40, ""},
+	} {
+		receipt, err := Update(context.Background(), limiter, "7", update, step.percent)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if receipt.Status != step.status {
+			t.Errorf("Expected status %q for %d%%, got %q", step.status, step.percent, receipt.Status)
+		}
+	}
+
+	if next.calls != 2 {
+		t.Errorf("Expected 2 updates delivered, got %d", next.calls)
+	}
+}
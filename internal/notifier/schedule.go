@@ -0,0 +1,311 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/filelock"
+)
+
+// StatusDeferred is reported for notifications held back during quiet hours
+const StatusDeferred = "deferred (quiet hours)"
+
+// ScheduleNotifier holds back less urgent notifications during quiet hours,
+// delivering them as digests once quiet hours end. The deferred notifications
+// are kept in a file, so that they survive restarts and reloads and are
+// delivered by whichever server is running when quiet hours end
+type ScheduleNotifier struct {
+	config *config.Config
+	next   Notifier
+	path   string
+
+	mu sync.Mutex
+	// digests is where digests are sent, next unless routed with RouteDigests
+	digests Notifier
+	timer   *time.Timer
+	now     func() time.Time
+}
+
+// deferred is a notification held back until quiet hours end. ID is set for
+// notifications updated in place, of which only the latest update is kept
+type deferred struct {
+	ID           string       `json:"id,omitempty"`
+	Notification Notification `json:"notification"`
+}
+
+// NewScheduleNotifier wraps next so that quiet hours are honored. Notifications
+// deferred before a restart are delivered when quiet hours end
+func NewScheduleNotifier(cfg *config.Config, next Notifier) *ScheduleNotifier {
+	return newScheduleNotifier(cfg, next, config.DeferredPath(), time.Now)
+}
+
+// newScheduleNotifier is NewScheduleNotifier keeping the deferred
+// notifications in path and telling the time with now
+func newScheduleNotifier(cfg *config.Config, next Notifier, path string, now func() time.Time) *ScheduleNotifier {
+	n := &ScheduleNotifier{
+		config: cfg,
+		next:   next,
+		path:   path,
+		now:    now,
+	}
+
+	pending, err := n.updatePending(func(pending []deferred) []deferred { return pending })
+	if err != nil {
+		log.Printf("[ScheduleNotifier] Failed to read deferred notifications: %v", err)
+	}
+	if pending > 0 {
+		if cfg.Notification.Verbose {
+			log.Printf("[ScheduleNotifier] Found %d deferred notifications in %s", pending, path)
+		}
+		n.mu.Lock()
+		n.arm()
+		n.mu.Unlock()
+	}
+	return n
+}
+
+// Unwrap returns the notifier used outside quiet hours
+func (n *ScheduleNotifier) Unwrap() Notifier {
+	return n.next
+}
+
+// Send delivers the notification unless it is quiet time and its level is
+// below the configured urgency, in which case it is deferred
+func (n *ScheduleNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	if isDigest(ctx) {
+		return n.next.Send(ctx, notification)
+	}
+	if receipt, ok := n.hold("", notification); ok {
+		return receipt, nil
+	}
+	return n.next.Send(ctx, notification)
+}
+
+// Update updates the notification unless it is quiet time and its level is
+// below the configured urgency, in which case it is deferred like Send
+func (n *ScheduleNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	if receipt, ok := n.hold(id, withPercent(notification, percent)); ok {
+		return receipt, nil
+	}
+	return Update(ctx, n.next, id, notification, percent)
+}
+
+// sendDigestsThrough sends the digests through top instead of the wrapped notifier
+func (n *ScheduleNotifier) sendDigestsThrough(top Notifier) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.digests = top
+}
+
+// Close drops the deferred updates of the notification and closes it through
+// the wrapped notifier, in case it was shown before quiet hours
+func (n *ScheduleNotifier) Close(ctx context.Context, id string) error {
+	dropped := false
+	if _, err := n.updatePending(func(pending []deferred) []deferred {
+		kept := slices.DeleteFunc(pending, func(d deferred) bool { return d.ID == id })
+		dropped = len(kept) < len(pending)
+		return kept
+	}); err != nil {
+		log.Printf("[ScheduleNotifier] Failed to drop deferred updates of %s: %v", id, err)
+	}
+
+	if err := Close(ctx, n.next, id); err != nil && !dropped {
+		return err
+	}
+	return nil
+}
+
+// hold defers the notification if it is quiet time and its level is below the
+// configured urgency, returning the receipt to report and whether it did
+func (n *ScheduleNotifier) hold(id string, notification Notification) (Receipt, bool) {
+	schedule := n.config.Notification.Schedule
+	now := n.now()
+	until, quiet := schedule.QuietUntil(now)
+	if !quiet || levelUrgency(n.config, notification.Level) >= urgencyByte(schedule.MinUrgency) {
+		return Receipt{}, false
+	}
+
+	if schedule.Deferred == config.DeferHistory {
+		if n.config.Notification.Verbose {
+			log.Printf("[ScheduleNotifier] Quiet hours, not delivering - Title: %s, Level: %s",
+				notification.Title, notification.Level)
+		}
+		return Receipt{Status: StatusDeferred}, true
+	}
+
+	queued, err := n.updatePending(func(pending []deferred) []deferred {
+		// A later update replaces the deferred one
+		index := slices.IndexFunc(pending, func(d deferred) bool { return id != "" && d.ID == id })
+		if index >= 0 {
+			pending[index].Notification = notification
+			return pending
+		}
+		return append(pending, deferred{ID: id, Notification: notification})
+	})
+	if err != nil {
+		// Delivering it now is better than losing it
+		log.Printf("[ScheduleNotifier] Failed to defer notification, delivering it: %v", err)
+		return Receipt{}, false
+	}
+	n.mu.Lock()
+	n.arm()
+	n.mu.Unlock()
+
+	if n.config.Notification.Verbose {
+		log.Printf("[ScheduleNotifier] Quiet hours, deferring until %s (%d pending) - Title: %s, Level: %s",
+			until.Format(time.RFC3339), queued, notification.Title, notification.Level)
+	}
+
+	return Receipt{Status: fmt.Sprintf("%s until %s", StatusDeferred, until.Format("Mon 15:04 MST"))}, true
+}
+
+// Flush delivers the deferred notifications if quiet hours are over. During
+// quiet hours they are kept in the file, for the next server to deliver
+func (n *ScheduleNotifier) Flush(ctx context.Context) error {
+	if _, quiet := n.config.Notification.Schedule.QuietUntil(n.now()); quiet {
+		n.mu.Lock()
+		if n.timer != nil {
+			n.timer.Stop()
+			n.timer = nil
+		}
+		n.mu.Unlock()
+		return nil
+	}
+	return n.deliver(ctx)
+}
+
+// arm starts the timer delivering the deferred notifications when quiet hours
+// end, or right away if they are over. The caller holds n.mu
+func (n *ScheduleNotifier) arm() {
+	if n.timer != nil {
+		return
+	}
+	now := n.now()
+	var delay time.Duration
+	if until, quiet := n.config.Notification.Schedule.QuietUntil(now); quiet {
+		delay = until.Sub(now)
+	}
+	n.timer = time.AfterFunc(delay, n.flushDeferred)
+}
+
+// flushDeferred delivers the deferred notifications when quiet hours end
+func (n *ScheduleNotifier) flushDeferred() {
+	// Nobody waits for the result, so a lost digest must at least be logged
+	if err := n.deliver(context.Background()); err != nil {
+		log.Printf("[ScheduleNotifier] Failed to deliver deferred notifications: %v", err)
+	}
+}
+
+// deliver sends the deferred notifications as one digest per workspace
+func (n *ScheduleNotifier) deliver(ctx context.Context) error {
+	n.mu.Lock()
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	digests := n.digests
+	n.mu.Unlock()
+
+	var pending []deferred
+	if _, err := n.updatePending(func(deferred []deferred) []deferred {
+		pending = deferred
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read deferred notifications: %w", err)
+	}
+
+	var workspaces []string
+	byWorkspace := make(map[string][]Notification)
+	for _, d := range pending {
+		notification := d.Notification
+		if _, ok := byWorkspace[notification.Workspace]; !ok {
+			workspaces = append(workspaces, notification.Workspace)
+		}
+		byWorkspace[notification.Workspace] = append(byWorkspace[notification.Workspace], notification)
+	}
+
+	var errs []error
+	for _, workspace := range workspaces {
+		digest := buildDigest(n.config, workspace, byWorkspace[workspace])
+		if n.config.Notification.Verbose {
+			log.Printf("[ScheduleNotifier] Quiet hours over, delivering %d deferred notifications - Title: %s",
+				len(byWorkspace[workspace]), digest.Title)
+		}
+		receipt, err := sendDigest(ctx, digests, n.next, digest)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to deliver deferred notifications: %w", err))
+		case receipt.Status != "":
+			errs = append(errs, fmt.Errorf("deferred notifications of %s not delivered: %s", digest.Title, receipt.Status))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// updatePending replaces the deferred notifications in the file with what
+// update returns for them, under a lock shared with other servers, and
+// returns how many are left
+func (n *ScheduleNotifier) updatePending(update func([]deferred) []deferred) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(n.path), 0700); err != nil {
+		return 0, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lock, err := filelock.Lock(n.path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Close()
+
+	data, err := os.ReadFile(n.path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read %s: %w", n.path, err)
+	}
+	var pending []deferred
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var d deferred
+		if len(line) > 0 && json.Unmarshal(line, &d) == nil {
+			pending = append(pending, d)
+		}
+	}
+
+	updated := update(pending)
+	var buf bytes.Buffer
+	for _, d := range updated {
+		line, err := json.Marshal(d)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode deferred notification: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if bytes.Equal(buf.Bytes(), data) {
+		return len(updated), nil
+	}
+
+	// Write to a temporary file and rename so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(n.path), filepath.Base(n.path)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", n.path, err)
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), n.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, fmt.Errorf("failed to write %s: %w", n.path, err)
+	}
+	return len(updated), nil
+}
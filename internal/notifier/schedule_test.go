@@ -0,0 +1,197 @@
+package notifier
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// newTestScheduleNotifier returns a schedule notifier with quiet hours every
+// night from 22:00 to 07:00 UTC and a controllable clock
+func newTestScheduleNotifier(t *testing.T, deferred string) (*ScheduleNotifier, *collectingNotifier, *time.Time) {
+	cfg := config.DefaultConfig()
+	cfg.Notification.Schedule = config.ScheduleConfig{
+		Enabled:    true,
+		Timezone:   "UTC",
+		MinUrgency: "critical",
+		Deferred:   deferred,
+		QuietHours: []config.QuietHours{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, Start: "22:00", End: "07:00"},
+		},
+	}
+	next := &collectingNotifier{}
+	now := time.Date(2025, 1, 6, 23, 0, 0, 0, time.UTC)
+	scheduler := newScheduleNotifier(cfg, next, filepath.Join(t.TempDir(), "deferred.jsonl"), func() time.Time { return now })
+	return scheduler, next, &now
+}
+
+func TestScheduleNotifier_DefersDuringQuietHours(t *testing.T) {
+	scheduler, next, _ := newTestScheduleNotifier(t, config.DeferDigest)
+
+	status := sendStatus(t, scheduler, Notification{Title: "Build", Message: "passed", Level: "success", Workspace: "projects/foo"})
+	if !strings.HasPrefix(status, StatusDeferred) || !strings.Contains(status, "07:00") {
+		t.Errorf("Expected deferred status until 07:00, got %q", status)
+	}
+	sendStatus(t, scheduler, Notification{Title: "Lint", Message: "warnings", Level: "warning", Workspace: "projects/foo"})
+
+	// Critical levels still get through
+	if status := sendStatus(t, scheduler, Notification{Title: "Deploy", Message: "failed", Level: "error", Workspace: "projects/foo"}); status != "" {
+		t.Errorf("Expected error level to be delivered during quiet hours, got %q", status)
+	}
+	if len(next.delivered()) != 1 {
+		t.Fatalf("Expected only the critical notification to be delivered, got %d", len(next.delivered()))
+	}
+}
+
+func TestScheduleNotifier_OutsideQuietHours(t *testing.T) {
+	scheduler, next, now := newTestScheduleNotifier(t, config.DeferDigest)
+	*now = time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	if status := sendStatus(t, scheduler, Notification{Title: "Build", Message: "passed", Level: "info"}); status != "" {
+		t.Errorf("Expected delivery outside quiet hours, got %q", status)
+	}
+	if len(next.delivered()) != 1 {
+		t.Error("Expected notification to reach the wrapped notifier")
+	}
+}
+
+func TestScheduleNotifier_DigestAfterQuietHours(t *testing.T) {
+	scheduler, next, now := newTestScheduleNotifier(t, config.DeferDigest)
+
+	sendStatus(t, scheduler, Notification{Title: "Build", Message: "passed", Level: "success", Workspace: "projects/foo"})
+	sendStatus(t, scheduler, Notification{Title: "Lint", Message: "warnings", Level: "warning", Workspace: "projects/foo"})
+	sendStatus(t, scheduler, Notification{Title: "Sync", Message: "done", Level: "info", Workspace: "projects/bar"})
+
+	// Flushing during quiet hours must not wake the user
+	if err := Flush(context.Background(), scheduler); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(next.delivered()) != 0 {
+		t.Fatalf("Expected nothing delivered during quiet hours, got %d", len(next.delivered()))
+	}
+
+	*now = time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)
+	if err := Flush(context.Background(), scheduler); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	delivered := next.delivered()
+	if len(delivered) != 2 {
+		t.Fatalf("Expected one digest per workspace, got %d", len(delivered))
+	}
+	if delivered[0].Title != "2 updates from projects/foo" || delivered[0].Level != "warning" {
+		t.Errorf("Unexpected digest: %+v", delivered[0])
+	}
+	if delivered[1].Title != "Sync" {
+		t.Errorf("Expected a single deferred notification to be delivered as is, got %+v", delivered[1])
+	}
+}
+
+func TestScheduleNotifier_HistoryOnly(t *testing.T) {
+	scheduler, next, now := newTestScheduleNotifier(t, config.DeferHistory)
+
+	if status := sendStatus(t, scheduler, Notification{Title: "Build", Message: "passed", Level: "info"}); status != StatusDeferred {
+		t.Errorf("Expected %q, got %q", StatusDeferred, status)
+	}
+
+	*now = time.Date(2025, 1, 7, 8, 0, 0, 0, time.UTC)
+	if err := Flush(context.Background(), scheduler); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(next.delivered()) != 0 {
+		t.Error("Expected notifications deferred to the history to never be delivered")
+	}
+}
+
+func TestScheduleNotifier_Restart(t *testing.T) {
+	scheduler, stopped, now := newTestScheduleNotifier(t, config.DeferDigest)
+	scheduler.config.Notification.History.Enabled = false
+
+	sendStatus(t, scheduler, Notification{Title: "Build", Message: "passed", Level: "info", Workspace: "projects/foo"})
+	sendStatus(t, scheduler, Notification{Title: "Lint", Message: "warnings", Level: "warning", Workspace: "projects/foo"})
+
+	// The server stops during quiet hours without waking the user
+	if err := Flush(context.Background(), scheduler); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// The next server delivers the digest once quiet hours end
+	next := &collectingNotifier{}
+	restarted := newScheduleNotifier(scheduler.config, next, scheduler.path, func() time.Time { return *now })
+	if len(next.delivered()) != 0 {
+		t.Fatal("Expected nothing delivered during quiet hours")
+	}
+	*now = time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)
+	if err := Flush(context.Background(), restarted); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if delivered := next.delivered(); len(delivered) != 1 || delivered[0].Title != "2 updates from projects/foo" {
+		t.Errorf("Expected the digest after the restart, got %+v", delivered)
+	}
+
+	// It is delivered once, not again by the previous server
+	if err := Flush(context.Background(), scheduler); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if delivered := stopped.delivered(); len(delivered) != 0 {
+		t.Errorf("Expected a single digest, got another %+v", delivered)
+	}
+}
+
+// statusNotifier reports every notification as not delivered with status
+type statusNotifier struct {
+	status string
+}
+
+func (s *statusNotifier) Send(ctx context.Context, n Notification) (Receipt, error) {
+	return Receipt{Status: s.status}, nil
+}
+
+func TestScheduleNotifier_DigestNotDelivered(t *testing.T) {
+	scheduler, _, now := newTestScheduleNotifier(t, config.DeferDigest)
+	scheduler.next = &statusNotifier{status: StatusRateLimited}
+
+	sendStatus(t, scheduler, Notification{Title: "Build", Message: "passed", Level: "info", Workspace: "projects/foo"})
+
+	// A digest that is held back further down is reported, not silently dropped
+	*now = time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)
+	err := Flush(context.Background(), scheduler)
+	if err == nil || !strings.Contains(err.Error(), StatusRateLimited) {
+		t.Errorf("Expected the digest to be reported as rate limited, got: %v", err)
+	}
+}
+
+func TestScheduleNotifier_DefersUpdates(t *testing.T) {
+	scheduler, next, now := newTestScheduleNotifier(t, config.DeferDigest)
+
+	// Only the latest update of a notification is kept
+	for _, percent := range []int{30, 60} {
+		receipt, err := Update(context.Background(), scheduler, "7", Notification{Title: "Build", Message: "compiling", Level: "info"}, percent)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(receipt.Status, StatusDeferred) {
+			t.Errorf("Expected the update to be deferred, got %q", receipt.Status)
+		}
+	}
+	if _, err := Update(context.Background(), scheduler, "8", Notification{Title: "Sync", Message: "copying", Level: "info"}, 10); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := Close(context.Background(), scheduler, "8"); err != nil {
+		t.Errorf("Expected closing a deferred notification to succeed, got %v", err)
+	}
+
+	*now = time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)
+	if err := Flush(context.Background(), scheduler); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	delivered := next.delivered()
+	if len(delivered) != 1 || delivered[0].Message != "compiling (60%)" {
+		t.Errorf("Expected only the latest update delivered, got %+v", delivered)
+	}
+}
@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/sound"
+)
+
+// soundTimeout bounds how long a sound may play
+const soundTimeout = 10 * time.Second
+
+// SoundNotifier plays the level's sound once a notification is delivered,
+// unless sounds are muted or it is quiet time
+type SoundNotifier struct {
+	config *config.Config
+	next   Notifier
+	player sound.Player
+	now    func() time.Time
+
+	// shown are the ids of the notifications updated in place, which only
+	// play their sound when they appear
+	mu    sync.Mutex
+	shown map[string]bool
+}
+
+// NewSoundNotifier wraps next so that level sounds are played through player
+func NewSoundNotifier(cfg *config.Config, next Notifier, player sound.Player) *SoundNotifier {
+	return &SoundNotifier{
+		config: cfg,
+		next:   next,
+		player: player,
+		now:    time.Now,
+		shown:  make(map[string]bool),
+	}
+}
+
+// Unwrap returns the notifier that shows the notification
+func (n *SoundNotifier) Unwrap() Notifier {
+	return n.next
+}
+
+// Send delivers the notification and starts playing its sound without waiting for it
+func (n *SoundNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	receipt, err := n.next.Send(ctx, notification)
+	if err != nil || receipt.Status != "" {
+		return receipt, err
+	}
+	n.playFor(notification)
+	return receipt, nil
+}
+
+// Update updates the notification, playing its sound on the first update
+// only so that progress steps do not chime one after the other
+func (n *SoundNotifier) Update(ctx context.Context, id string, notification Notification, percent int) (Receipt, error) {
+	receipt, err := Update(ctx, n.next, id, notification, percent)
+	if err != nil || receipt.Status != "" {
+		return receipt, err
+	}
+
+	n.mu.Lock()
+	first := !n.shown[id]
+	n.shown[id] = true
+	n.mu.Unlock()
+
+	if first {
+		n.playFor(notification)
+	}
+	return receipt, nil
+}
+
+// Close closes the notification through the wrapped notifier
+func (n *SoundNotifier) Close(ctx context.Context, id string) error {
+	n.mu.Lock()
+	delete(n.shown, id)
+	n.mu.Unlock()
+	return Close(ctx, n.next, id)
+}
+
+// playFor starts playing the sound of the notification's level, unless sounds
+// are muted or it is quiet time. Levels that quiet hours let through, such as
+// critical ones, are played during quiet hours too
+func (n *SoundNotifier) playFor(notification Notification) {
+	name := n.config.Notification.Levels[notification.Level].Sound
+	if name == "" || n.config.Notification.Sound.Mute {
+		return
+	}
+	schedule := n.config.Notification.Schedule
+	if _, quiet := schedule.QuietUntil(n.now()); quiet && levelUrgency(n.config, notification.Level) < urgencyByte(schedule.MinUrgency) {
+		if n.config.Notification.Verbose {
+			log.Printf("[SoundNotifier] Quiet hours, not playing %s", name)
+		}
+		return
+	}
+
+	go n.play(name)
+}
+
+// play plays the sound, logging failures since nobody waits for the outcome
+func (n *SoundNotifier) play(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), soundTimeout)
+	defer cancel()
+
+	if n.config.Notification.Verbose {
+		log.Printf("[SoundNotifier] Playing %s", name)
+	}
+	if err := n.player.Play(ctx, name); err != nil && n.config.Notification.Verbose {
+		log.Printf("[SoundNotifier] Failed to play %s: %v", name, err)
+	}
+}
@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// fakePlayer reports the sounds it is asked to play
+type fakePlayer struct {
+	played chan string
+}
+
+func (f *fakePlayer) Play(ctx context.Context, sound string) error {
+	f.played <- sound
+	return nil
+}
+
+// newTestSoundNotifier gives the error level a sound and returns the notifier and its player
+func newTestSoundNotifier(next Notifier) (*SoundNotifier, *fakePlayer) {
+	cfg := config.DefaultConfig()
+	errorLevel := cfg.Notification.Levels["error"]
+	errorLevel.Sound = "dialog-error"
+	cfg.Notification.Levels["error"] = errorLevel
+
+	player := &fakePlayer{played: make(chan string, 1)}
+	notifier := NewSoundNotifier(cfg, next, player)
+	notifier.now = func() time.Time { return time.Date(2025, 1, 6, 23, 0, 0, 0, time.UTC) }
+	return notifier, player
+}
+
+// expectPlayed waits for the asynchronously played sound
+func expectPlayed(t *testing.T, player *fakePlayer, expected string) {
+	t.Helper()
+	select {
+	case sound := <-player.played:
+		if sound != expected {
+			t.Errorf("Expected sound %q, got %q", expected, sound)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected sound %q to be played", expected)
+	}
+}
+
+// expectSilence checks that no sound is played
+func expectSilence(t *testing.T, player *fakePlayer) {
+	t.Helper()
+	select {
+	case sound := <-player.played:
+		t.Errorf("Expected no sound, got %q", sound)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSoundNotifier_PlaysLevelSound(t *testing.T) {
+	notifier, player := newTestSoundNotifier(&stubNotifier{})
+
+	sendStatus(t, notifier, Notification{Title: "Build failed", Message: "m", Level: "error"})
+	expectPlayed(t, player, "dialog-error")
+
+	// Levels without a sound stay silent
+	sendStatus(t, notifier, Notification{Title: "Build passed", Message: "m", Level: "success"})
+	expectSilence(t, player)
+}
+
+func TestSoundNotifier_Mute(t *testing.T) {
+	notifier, player := newTestSoundNotifier(&stubNotifier{})
+	notifier.config.Notification.Sound.Mute = true
+
+	sendStatus(t, notifier, Notification{Title: "Build failed", Message: "m", Level: "error"})
+	expectSilence(t, player)
+}
+
+func TestSoundNotifier_QuietHours(t *testing.T) {
+	notifier, player := newTestSoundNotifier(&stubNotifier{})
+	notifier.config.Notification.Schedule = config.ScheduleConfig{
+		Enabled:    true,
+		Timezone:   "UTC",
+		MinUrgency: "critical",
+		Deferred:   config.DeferDigest,
+		QuietHours: []config.QuietHours{{Days: []string{"mon"}, Start: "22:00", End: "07:00"}},
+	}
+
+	// Critical notifications show during quiet hours and keep their sound
+	sendStatus(t, notifier, Notification{Title: "Build failed", Message: "m", Level: "error"})
+	expectPlayed(t, player, "dialog-error")
+
+	// Levels below the minimum urgency are silent
+	warning := notifier.config.Notification.Levels["warning"]
+	warning.Sound = "dialog-warning"
+	notifier.config.Notification.Levels["warning"] = warning
+	sendStatus(t, notifier, Notification{Title: "Disk almost full", Message: "m", Level: "warning"})
+	expectSilence(t, player)
+}
+
+func TestSoundNotifier_NotDelivered(t *testing.T) {
+	notifier, player := newTestSoundNotifier(&stubNotifier{err: errors.New("no notification daemon")})
+
+	if _, err := notifier.Send(context.Background(), Notification{Title: "Build failed", Message: "m", Level: "error"}); err == nil {
+		t.Fatal("Expected the delivery error to be returned")
+	}
+	expectSilence(t, player)
+}
+
+func TestSoundNotifier_UpdatesPlayOnce(t *testing.T) {
+	notifier, player := newTestSoundNotifier(&closingNotifier{})
+	notifier.now = func() time.Time { return time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC) }
+
+	update := Notification{Title: "Deploy", Message: "rolling back", Level: "error"}
+	if _, err := Update(context.Background(), notifier, "7", update, 10); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectPlayed(t, player, "dialog-error")
+
+	// Later steps of the same notification are silent
+	if _, err := Update(context.Background(), notifier, "7", update, 50); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectSilence(t, player)
+
+	// Once closed, the id may be used for a new notification
+	if err := Close(context.Background(), notifier, "7"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := Update(context.Background(), notifier, "7", update, 10); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectPlayed(t, player, "dialog-error")
+}
@@ -0,0 +1,187 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// TerminalNotifier writes notifications to a terminal as escape sequences,
+// which terminal emulators turn into desktop notifications. This works over
+// SSH, where the desktop is on the other end of the connection
+type TerminalNotifier struct {
+	config   *config.Config
+	terminal config.TerminalConfig
+	// open returns the terminal to write to
+	open func() (io.WriteCloser, error)
+	// tmux reports whether the server runs inside tmux
+	tmux bool
+	// ids numbers OSC 99 notifications, whose title and body are sent separately
+	ids atomic.Uint64
+}
+
+// NewTerminalNotifier creates a notifier writing to the configured terminal
+func NewTerminalNotifier(cfg *config.Config, terminal config.TerminalConfig) *TerminalNotifier {
+	n := &TerminalNotifier{
+		config:   cfg,
+		terminal: terminal,
+		open:     openControllingTerminal,
+		tmux:     os.Getenv("TMUX") != "",
+	}
+	if terminal.TTY != "" {
+		n.open = func() (io.WriteCloser, error) {
+			tty, err := os.OpenFile(terminal.TTY, os.O_WRONLY, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open terminal: %w", err)
+			}
+			return tty, nil
+		}
+	}
+	return n
+}
+
+// openControllingTerminal opens the terminal of the session, even when stdout
+// is redirected, falling back to the parent's terminal for detached processes
+func openControllingTerminal() (io.WriteCloser, error) {
+	path := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		path = "CONOUT$"
+	}
+	tty, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err == nil {
+		return tty, nil
+	}
+
+	if parent := parentTerminal(); parent != "" {
+		if tty, parentErr := os.OpenFile(parent, os.O_WRONLY, 0); parentErr == nil {
+			return tty, nil
+		}
+	}
+	return nil, fmt.Errorf("no controlling terminal: %w", err)
+}
+
+// parentTerminal returns the terminal device the parent process is attached
+// to, if it can be found in /proc
+func parentTerminal() string {
+	for _, fd := range []string{"0", "1", "2"} {
+		target, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%s", os.Getppid(), fd))
+		if err == nil && (strings.HasPrefix(target, "/dev/pts/") || strings.HasPrefix(target, "/dev/tty")) {
+			return target
+		}
+	}
+	return ""
+}
+
+// Send writes the escape sequence for the notification to the terminal
+func (n *TerminalNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	sequence := n.sequence(notification)
+
+	if n.config.Notification.Verbose {
+		log.Printf("[TerminalNotifier] Writing %s to the terminal (tmux passthrough: %v) - Title: %s, Level: %s",
+			n.sequenceName(), n.passthrough(), notification.Title, notification.Level)
+	}
+
+	tty, err := n.open()
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer tty.Close()
+
+	if _, err := io.WriteString(tty, sequence); err != nil {
+		return Receipt{}, fmt.Errorf("failed to write to terminal: %w", err)
+	}
+	return Receipt{}, nil
+}
+
+// sequenceName returns the configured sequence, defaulting to OSC 9
+func (n *TerminalNotifier) sequenceName() string {
+	if n.terminal.Sequence == "" {
+		return config.TerminalSequenceOSC9
+	}
+	return n.terminal.Sequence
+}
+
+// passthrough reports whether sequences must be wrapped for tmux
+func (n *TerminalNotifier) passthrough() bool {
+	switch n.terminal.Tmux {
+	case config.TmuxAlways:
+		return true
+	case config.TmuxNever:
+		return false
+	default:
+		return n.tmux
+	}
+}
+
+// sequence builds the escape sequences for the notification
+func (n *TerminalNotifier) sequence(notification Notification) string {
+	title := terminalText(notification.Title)
+	message := terminalText(notification.Message)
+
+	var sequences []string
+	switch n.sequenceName() {
+	case config.TerminalSequenceBell:
+		// tmux forwards the bell on its own
+		return "\a"
+	case config.TerminalSequenceOSC777:
+		// The title ends at the first semicolon, the message can contain them
+		sequences = []string{fmt.Sprintf("\x1b]777;notify;%s;%s\x1b\\", strings.ReplaceAll(title, ";", ","), message)}
+	case config.TerminalSequenceOSC99:
+		// kitty shows the notification once the body, sent with d=1, completes it
+		id := n.ids.Add(1)
+		urgency := kittyUrgency(n.config.Notification.Levels[notification.Level].Urgency)
+		sequences = []string{
+			fmt.Sprintf("\x1b]99;i=%d:d=0:u=%d;%s\x1b\\", id, urgency, title),
+			fmt.Sprintf("\x1b]99;i=%d:d=1:p=body;%s\x1b\\", id, message),
+		}
+	default:
+		// OSC 9 has no separate title
+		sequences = []string{fmt.Sprintf("\x1b]9;%s: %s\x1b\\", title, message)}
+	}
+
+	if n.passthrough() {
+		for i, sequence := range sequences {
+			sequences[i] = tmuxPassthrough(sequence)
+		}
+	}
+	return strings.Join(sequences, "")
+}
+
+// tmuxPassthrough wraps a sequence so tmux hands it to the outer terminal,
+// doubling the escape characters inside it
+func tmuxPassthrough(sequence string) string {
+	return "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
+}
+
+// kittyUrgency maps urgency to kitty's u= values (0 low, 1 normal, 2 critical)
+func kittyUrgency(urgency string) int {
+	switch urgency {
+	case "low":
+		return 0
+	case "critical":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// terminalText strips control characters, which could end the escape
+// sequence early and let the text drive the terminal
+func terminalText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return ' '
+		case r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0):
+			return -1
+		}
+		return r
+	}, text)
+}
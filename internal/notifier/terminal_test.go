@@ -0,0 +1,135 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// nopCloser turns a buffer into a fake terminal
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// newTestTerminalNotifier returns a terminal notifier writing to a buffer
+func newTestTerminalNotifier(terminal config.TerminalConfig) (*TerminalNotifier, *bytes.Buffer) {
+	var buf bytes.Buffer
+	n := NewTerminalNotifier(config.DefaultConfig(), terminal)
+	n.open = func() (io.WriteCloser, error) { return nopCloser{&buf}, nil }
+	n.tmux = false
+	return n, &buf
+}
+
+func TestTerminalNotifier_Sequences(t *testing.T) {
+	tests := []struct {
+		sequence string
+		expected string
+	}{
+		{"", "\x1b]9;Build: passed; 3 tests\x1b\\"},
+		{config.TerminalSequenceOSC9, "\x1b]9;Build: passed; 3 tests\x1b\\"},
+		{config.TerminalSequenceOSC777, "\x1b]777;notify;Build;passed; 3 tests\x1b\\"},
+		{config.TerminalSequenceOSC99, "\x1b]99;i=1:d=0:u=1;Build\x1b\\\x1b]99;i=1:d=1:p=body;passed; 3 tests\x1b\\"},
+		{config.TerminalSequenceBell, "\a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sequence, func(t *testing.T) {
+			n, buf := newTestTerminalNotifier(config.TerminalConfig{Sequence: tt.sequence})
+			if _, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed; 3 tests", Level: "info"}); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestTerminalNotifier_StripsControlCharacters(t *testing.T) {
+	n, buf := newTestTerminalNotifier(config.TerminalConfig{Sequence: config.TerminalSequenceOSC777})
+	if _, err := n.Send(context.Background(), Notification{Title: "a;b\x1b]0;pwned\a", Message: "line1\nline2\u009c", Level: "info"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	expected := "\x1b]777;notify;a,b]0,pwned;line1 line2\x1b\\"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestTerminalNotifier_NoTerminal(t *testing.T) {
+	n := NewTerminalNotifier(config.DefaultConfig(), config.TerminalConfig{})
+	n.open = func() (io.WriteCloser, error) { return nil, errors.New("no controlling terminal") }
+	if _, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"}); err == nil {
+		t.Error("Expected error without a terminal")
+	}
+}
+
+func TestTerminalNotifier_TmuxPassthrough(t *testing.T) {
+	tests := []struct {
+		mode     string
+		inTmux   bool
+		expected string
+	}{
+		{config.TmuxAuto, true, "\x1bPtmux;\x1b\x1b]9;Build: passed\x1b\x1b\\\x1b\\"},
+		{config.TmuxAuto, false, "\x1b]9;Build: passed\x1b\\"},
+		{config.TmuxAlways, false, "\x1bPtmux;\x1b\x1b]9;Build: passed\x1b\x1b\\\x1b\\"},
+		{config.TmuxNever, true, "\x1b]9;Build: passed\x1b\\"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			n, buf := newTestTerminalNotifier(config.TerminalConfig{Tmux: tt.mode})
+			n.tmux = tt.inTmux
+			if _, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"}); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestTerminalNotifier_KittyUrgency(t *testing.T) {
+	n, buf := newTestTerminalNotifier(config.TerminalConfig{Sequence: config.TerminalSequenceOSC99})
+	if _, err := n.Send(context.Background(), Notification{Title: "Deploy", Message: "failed", Level: "error"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	expected := "\x1b]99;i=1:d=0:u=2;Deploy\x1b\\\x1b]99;i=1:d=1:p=body;failed\x1b\\"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestTerminalNotifier_ConfiguredTTY(t *testing.T) {
+	// A regular file stands in for the terminal device
+	tty := filepath.Join(t.TempDir(), "tty")
+	if err := os.WriteFile(tty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewTerminalNotifier(config.DefaultConfig(), config.TerminalConfig{TTY: tty, Tmux: config.TmuxNever})
+	if _, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	written, err := os.ReadFile(tty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != "\x1b]9;Build: passed\x1b\\" {
+		t.Errorf("Unexpected terminal output: %q", written)
+	}
+
+	n = NewTerminalNotifier(config.DefaultConfig(), config.TerminalConfig{TTY: filepath.Join(t.TempDir(), "missing")})
+	if _, err := n.Send(context.Background(), Notification{Title: "Build", Message: "passed", Level: "info"}); err == nil {
+		t.Error("Expected error for a missing terminal device")
+	}
+}
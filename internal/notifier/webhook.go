@@ -0,0 +1,189 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// Webhook defaults
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	defaultWebhookBackoff = time.Second
+)
+
+// WebhookNotifier posts notifications to a URL, formatted for the receiving service
+type WebhookNotifier struct {
+	config  *config.Config
+	webhook config.WebhookConfig
+	client  *http.Client
+	// backoff is the delay before the first retry, doubled for each further retry
+	backoff time.Duration
+}
+
+// NewWebhookNotifier creates a notifier posting to the configured webhook
+func NewWebhookNotifier(cfg *config.Config, webhook config.WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{
+		config:  cfg,
+		webhook: webhook,
+		client:  &http.Client{},
+		backoff: defaultWebhookBackoff,
+	}
+}
+
+// Send posts the notification, retrying network errors and server errors
+func (n *WebhookNotifier) Send(ctx context.Context, notification Notification) (Receipt, error) {
+	if n.config.Notification.Verbose {
+		log.Printf("[WebhookNotifier] Posting notification to %s (%s) - Title: %s, Level: %s",
+			n.webhook.URL, n.format(), notification.Title, notification.Level)
+		if len(notification.Actions) > 0 {
+			log.Printf("[WebhookNotifier] Actions are not supported by webhooks, ignoring: %v", notification.Actions)
+		}
+	}
+
+	body, headers, err := n.payload(notification)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("failed to build webhook payload: %w", err)
+	}
+
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, body, headers)
+		if err == nil {
+			return Receipt{}, nil
+		}
+		if !retry || attempt >= n.webhook.Retries {
+			return Receipt{}, err
+		}
+
+		if n.config.Notification.Verbose {
+			log.Printf("[WebhookNotifier] Attempt %d failed, retrying in %s: %v", attempt+1, backoff, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return Receipt{}, fmt.Errorf("webhook request cancelled: %w", ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one request and reports whether a failure is worth retrying
+func (n *WebhookNotifier) post(ctx context.Context, body []byte, headers map[string]string) (retry bool, err error) {
+	timeout := defaultWebhookTimeout
+	if n.webhook.Timeout > 0 {
+		timeout = time.Duration(n.webhook.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	// Configured headers come last so they can override the format's defaults
+	for key, value := range n.webhook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// format returns the configured payload format, defaulting to plain JSON
+func (n *WebhookNotifier) format() string {
+	if n.webhook.Format == "" {
+		return config.WebhookFormatJSON
+	}
+	return n.webhook.Format
+}
+
+// payload encodes the notification for the configured format
+func (n *WebhookNotifier) payload(notification Notification) ([]byte, map[string]string, error) {
+	urgency := n.config.Notification.Levels[notification.Level].Urgency
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+
+	var payload any
+	switch n.format() {
+	case config.WebhookFormatSlack:
+		payload = map[string]string{
+			"text": fmt.Sprintf("*%s*\n%s", notification.Title, notification.Message),
+		}
+	case config.WebhookFormatDiscord:
+		payload = map[string]string{
+			"content": fmt.Sprintf("**%s**\n%s", notification.Title, notification.Message),
+		}
+	case config.WebhookFormatNtfy:
+		// ntfy takes the message as the body and everything else as headers
+		return []byte(notification.Message), map[string]string{
+			"Content-Type": "text/plain; charset=utf-8",
+			"Title":        mime.QEncoding.Encode("utf-8", notification.Title),
+			"Priority":     fmt.Sprint(ntfyPriority(urgency)),
+			"Tags":         notification.Level,
+		}, nil
+	case config.WebhookFormatGotify:
+		payload = map[string]any{
+			"title":    notification.Title,
+			"message":  notification.Message,
+			"priority": gotifyPriority(urgency),
+		}
+	default:
+		payload = map[string]any{
+			"title":     notification.Title,
+			"message":   notification.Message,
+			"level":     notification.Level,
+			"urgency":   urgency,
+			"workspace": notification.Workspace,
+			"client":    notification.Client,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	return body, jsonHeaders, err
+}
+
+// ntfyPriority maps urgency to ntfy priorities (1 min to 5 max)
+func ntfyPriority(urgency string) int {
+	switch urgency {
+	case "low":
+		return 2
+	case "critical":
+		return 5
+	default:
+		return 3
+	}
+}
+
+// gotifyPriority maps urgency to Gotify priorities (0 to 10)
+func gotifyPriority(urgency string) int {
+	switch urgency {
+	case "low":
+		return 2
+	case "critical":
+		return 8
+	default:
+		return 5
+	}
+}
@@ -0,0 +1,182 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// webhookRequest is a request received by the test webhook server
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestWebhook starts a server answering with the given status codes in turn,
+// then 200, and returns the notifier posting to it and the received requests
+func newTestWebhook(t *testing.T, webhook config.WebhookConfig, statuses ...int) (*WebhookNotifier, func() []webhookRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, webhookRequest{header: r.Header, body: body})
+		status := http.StatusOK
+		if len(requests) <= len(statuses) {
+			status = statuses[len(requests)-1]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	webhook.URL = server.URL
+	notifier := NewWebhookNotifier(config.DefaultConfig(), webhook)
+	notifier.backoff = time.Millisecond
+	return notifier, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), requests...)
+	}
+}
+
+func TestWebhookNotifier_Formats(t *testing.T) {
+	notification := Notification{Title: "Build failed", Message: "3 tests failing", Level: "error", Workspace: "projects/foo"}
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T, req webhookRequest)
+	}{
+		{config.WebhookFormatSlack, func(t *testing.T, req webhookRequest) {
+			expectJSON(t, req.body, map[string]any{"text": "*Build failed*\n3 tests failing"})
+		}},
+		{config.WebhookFormatDiscord, func(t *testing.T, req webhookRequest) {
+			expectJSON(t, req.body, map[string]any{"content": "**Build failed**\n3 tests failing"})
+		}},
+		{config.WebhookFormatGotify, func(t *testing.T, req webhookRequest) {
+			expectJSON(t, req.body, map[string]any{"title": "Build failed", "message": "3 tests failing", "priority": float64(8)})
+		}},
+		{config.WebhookFormatNtfy, func(t *testing.T, req webhookRequest) {
+			if string(req.body) != "3 tests failing" {
+				t.Errorf("Expected the message as body, got %q", req.body)
+			}
+			if req.header.Get("Title") != "Build failed" || req.header.Get("Priority") != "5" || req.header.Get("Tags") != "error" {
+				t.Errorf("Unexpected ntfy headers: %v", req.header)
+			}
+		}},
+		{"", func(t *testing.T, req webhookRequest) {
+			var payload map[string]any
+			if err := json.Unmarshal(req.body, &payload); err != nil {
+				t.Fatalf("Invalid JSON payload: %v", err)
+			}
+			if payload["title"] != "Build failed" || payload["level"] != "error" || payload["workspace"] != "projects/foo" {
+				t.Errorf("Unexpected JSON payload: %v", payload)
+			}
+			if req.header.Get("Content-Type") != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", req.header.Get("Content-Type"))
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			notifier, requests := newTestWebhook(t, config.WebhookConfig{Format: tt.format})
+			if _, err := notifier.Send(context.Background(), notification); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			received := requests()
+			if len(received) != 1 {
+				t.Fatalf("Expected 1 request, got %d", len(received))
+			}
+			tt.check(t, received[0])
+		})
+	}
+}
+
+func expectJSON(t *testing.T, body []byte, expected map[string]any) {
+	t.Helper()
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Invalid JSON payload: %v", err)
+	}
+	for key, value := range expected {
+		if payload[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, payload[key])
+		}
+	}
+}
+
+func TestWebhookNotifier_Headers(t *testing.T) {
+	notifier, requests := newTestWebhook(t, config.WebhookConfig{
+		Format:  config.WebhookFormatGotify,
+		Headers: map[string]string{"X-Gotify-Key": "secret"},
+	})
+	if _, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if key := requests()[0].header.Get("X-Gotify-Key"); key != "secret" {
+		t.Errorf("Expected configured header, got %q", key)
+	}
+}
+
+func TestWebhookNotifier_RetriesServerErrors(t *testing.T) {
+	notifier, requests := newTestWebhook(t, config.WebhookConfig{Retries: 2},
+		http.StatusBadGateway, http.StatusTooManyRequests)
+
+	if _, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"}); err != nil {
+		t.Fatalf("Expected success after retries, got: %v", err)
+	}
+	if len(requests()) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(requests()))
+	}
+}
+
+func TestWebhookNotifier_GivesUp(t *testing.T) {
+	notifier, requests := newTestWebhook(t, config.WebhookConfig{Retries: 1},
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	if _, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"}); err == nil {
+		t.Error("Expected error once retries are exhausted")
+	}
+	if len(requests()) != 2 {
+		t.Errorf("Expected 2 attempts, got %d", len(requests()))
+	}
+}
+
+func TestWebhookNotifier_NoRetryOnClientError(t *testing.T) {
+	notifier, requests := newTestWebhook(t, config.WebhookConfig{Retries: 3}, http.StatusUnauthorized)
+
+	if _, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"}); err == nil {
+		t.Error("Expected error for 401")
+	}
+	if len(requests()) != 1 {
+		t.Errorf("Expected client errors not to be retried, got %d attempts", len(requests()))
+	}
+}
+
+func TestWebhookNotifier_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	notifier := NewWebhookNotifier(config.DefaultConfig(), config.WebhookConfig{URL: server.URL, Timeout: 1})
+	start := time.Now()
+	if _, err := notifier.Send(context.Background(), Notification{Title: "T", Message: "M", Level: "info"}); err == nil {
+		t.Error("Expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the request to time out after about 1s, took %s", elapsed)
+	}
+}
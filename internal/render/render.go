@@ -0,0 +1,60 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+// Data holds the values available to notification templates
+type Data struct {
+	Title     string
+	Message   string
+	Level     string
+	Timestamp time.Time
+	Workspace string
+	Client    Client
+}
+
+// Client identifies the MCP client that requested the notification
+type Client struct {
+	Name    string
+	Version string
+}
+
+// Render renders the notification title and body from the configured templates.
+// A template that fails to parse or execute falls back to the raw title or message,
+// and the error is returned so the caller can report it
+func Render(tmpl config.Template, data Data) (title, body string, err error) {
+	title, titleErr := execute("title", tmpl.Title, data, data.Title)
+	body, bodyErr := execute("body", tmpl.BodyTemplate(), data, data.Message)
+
+	if titleErr != nil {
+		err = titleErr
+	} else if bodyErr != nil {
+		err = bodyErr
+	}
+	return title, body, err
+}
+
+// execute renders a single template, returning fallback if it is empty or fails
+func execute(name, text string, data Data, fallback string) (string, error) {
+	if text == "" {
+		return fallback, nil
+	}
+
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return fallback, fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+
+	var out strings.Builder
+	if err := t.Execute(&out, data); err != nil {
+		return fallback, fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+
+	return out.String(), nil
+}
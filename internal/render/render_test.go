@@ -0,0 +1,139 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/mcp-desktop-notification/internal/config"
+)
+
+func testData() Data {
+	return Data{
+		Title:     "Build",
+		Message:   "All tests passed",
+		Level:     "success",
+		Timestamp: time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC),
+		Workspace: "workspace/foo",
+		Client:    Client{Name: "claude-code", Version: "1.2.3"},
+	}
+}
+
+func TestRender_DefaultTemplates(t *testing.T) {
+	title, body, err := Render(config.DefaultConfig().Notification.Template, testData())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if title != "Build" {
+		t.Errorf("Expected title 'Build', got %q", title)
+	}
+	if body != "All tests passed" {
+		t.Errorf("Expected body 'All tests passed', got %q", body)
+	}
+}
+
+func TestRender_AllFields(t *testing.T) {
+	tmpl := config.Template{
+		Title: "[{{.Workspace}}] {{.Title}}",
+		Body:  "{{.Message}} ({{.Level}} at {{.Timestamp.Format \"15:04\"}} from {{.Client.Name}} {{.Client.Version}})",
+	}
+
+	title, body, err := Render(tmpl, testData())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if title != "[workspace/foo] Build" {
+		t.Errorf("Unexpected title: %q", title)
+	}
+	expected := "All tests passed (success at 15:09 from claude-code 1.2.3)"
+	if body != expected {
+		t.Errorf("Expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRender_BodyOverridesDefault(t *testing.T) {
+	tmpl := config.Template{
+		Default: "default: {{.Message}}",
+		Body:    "body: {{.Message}}",
+	}
+
+	_, body, err := Render(tmpl, testData())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if body != "body: All tests passed" {
+		t.Errorf("Expected body template to win over default, got %q", body)
+	}
+
+	tmpl.Body = ""
+	_, body, _ = Render(tmpl, testData())
+	if body != "default: All tests passed" {
+		t.Errorf("Expected default template when body is unset, got %q", body)
+	}
+}
+
+func TestRender_EmptyTemplatesUseRawValues(t *testing.T) {
+	title, body, err := Render(config.Template{}, testData())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if title != "Build" || body != "All tests passed" {
+		t.Errorf("Expected raw title/message, got %q / %q", title, body)
+	}
+}
+
+func TestRender_RuntimeErrorFallsBack(t *testing.T) {
+	tmpl := config.Template{
+		Title: "{{.Title}}",
+		Body:  "{{.Unknown}}",
+	}
+
+	title, body, err := Render(tmpl, testData())
+	if err == nil {
+		t.Fatal("Expected error for unknown template field")
+	}
+	if !strings.Contains(err.Error(), "body") {
+		t.Errorf("Expected error to name the body template, got: %v", err)
+	}
+	if title != "Build" {
+		t.Errorf("Expected rendered title to be kept, got %q", title)
+	}
+	if body != "All tests passed" {
+		t.Errorf("Expected fallback to raw message, got %q", body)
+	}
+}
+
+func TestRender_ParseErrorFallsBack(t *testing.T) {
+	tmpl := config.Template{Title: "{{.Title"}
+
+	title, _, err := Render(tmpl, testData())
+	if err == nil {
+		t.Fatal("Expected parse error")
+	}
+	if title != "Build" {
+		t.Errorf("Expected fallback to raw title, got %q", title)
+	}
+}
+
+func TestRender_ConfigAcceptsAllFields(t *testing.T) {
+	// Configuration validation rejects unknown fields, so it must know every field of Data
+	var fields []string
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Data{})) {
+		if field.Type == reflect.TypeOf(Client{}) {
+			for _, clientField := range reflect.VisibleFields(field.Type) {
+				fields = append(fields, "{{."+field.Name+"."+clientField.Name+"}}")
+			}
+			continue
+		}
+		fields = append(fields, "{{."+field.Name+"}}")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Notification.Template.Body = strings.Join(fields, " ")
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected every Data field to be accepted in templates, got: %v", err)
+	}
+}
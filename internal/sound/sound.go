@@ -0,0 +1,101 @@
+package sound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/gen2brain/beeep"
+)
+
+// ErrNoPlayer is returned when no program able to play the sound is installed
+var ErrNoPlayer = errors.New("no sound player available")
+
+// Player plays a sound, given as a freedesktop sound theme name (e.g.
+// "message-new-instant") or a file path
+type Player interface {
+	Play(ctx context.Context, sound string) error
+}
+
+// NewPlayer returns the default player: the system's sound tools, falling
+// back to a plain beep when they are missing or fail
+func NewPlayer() Player {
+	return FallbackPlayer{SystemPlayer{}, BeepPlayer{}}
+}
+
+// SystemPlayer plays sounds with the first available command line player
+type SystemPlayer struct{}
+
+// Play runs a player suited to the sound and the platform
+func (SystemPlayer) Play(ctx context.Context, sound string) error {
+	for _, argv := range playerCommands(sound) {
+		path, err := exec.LookPath(argv[0])
+		if err != nil {
+			continue
+		}
+		if err := exec.CommandContext(ctx, path, argv[1:]...).Run(); err != nil {
+			return fmt.Errorf("%s failed: %w", argv[0], err)
+		}
+		return nil
+	}
+	return ErrNoPlayer
+}
+
+// playerCommands lists the commands that can play the sound, in order of preference
+func playerCommands(sound string) [][]string {
+	if !IsFile(sound) {
+		// Theme sounds are looked up by libcanberra
+		return [][]string{{"canberra-gtk-play", "-i", sound}}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"afplay", sound}}
+	case "windows":
+		return nil
+	default:
+		return [][]string{
+			{"paplay", sound},
+			{"pw-play", sound},
+			{"canberra-gtk-play", "-f", sound},
+			{"aplay", "-q", sound},
+		}
+	}
+}
+
+// IsFile reports whether sound refers to a file rather than a theme sound name
+func IsFile(sound string) bool {
+	if filepath.IsAbs(sound) || filepath.Ext(sound) != "" {
+		return true
+	}
+	_, err := os.Stat(sound)
+	return err == nil
+}
+
+// BeepPlayer ignores the sound and beeps
+type BeepPlayer struct{}
+
+// Play beeps with the platform's default tone
+func (BeepPlayer) Play(ctx context.Context, sound string) error {
+	return beeep.Beep(beeep.DefaultFreq, beeep.DefaultDuration)
+}
+
+// FallbackPlayer tries each player in turn until one succeeds
+type FallbackPlayer []Player
+
+// Play returns nil once a player succeeds, or the errors of all of them
+func (f FallbackPlayer) Play(ctx context.Context, sound string) error {
+	var errs []error
+	for _, player := range f {
+		err := player.Play(ctx, sound)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,61 @@
+package sound
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// stubPlayer returns a fixed error and counts its calls
+type stubPlayer struct {
+	err   error
+	calls int
+}
+
+func (s *stubPlayer) Play(ctx context.Context, sound string) error {
+	s.calls++
+	return s.err
+}
+
+func TestFallbackPlayer(t *testing.T) {
+	failing := &stubPlayer{err: ErrNoPlayer}
+	working := &stubPlayer{}
+	unused := &stubPlayer{}
+
+	if err := (FallbackPlayer{failing, working, unused}).Play(context.Background(), "bell"); err != nil {
+		t.Fatalf("Expected the fallback to succeed, got: %v", err)
+	}
+	if failing.calls != 1 || working.calls != 1 || unused.calls != 0 {
+		t.Errorf("Unexpected calls: %d, %d, %d", failing.calls, working.calls, unused.calls)
+	}
+
+	other := &stubPlayer{err: errors.New("device busy")}
+	err := (FallbackPlayer{failing, other}).Play(context.Background(), "bell")
+	if !errors.Is(err, ErrNoPlayer) || !errors.Is(err, other.err) {
+		t.Errorf("Expected the errors of all players, got: %v", err)
+	}
+}
+
+func TestIsFile(t *testing.T) {
+	tests := []struct {
+		sound    string
+		expected bool
+	}{
+		{"message-new-instant", false},
+		{"complete.oga", true},
+		{filepath.Join(string(filepath.Separator), "usr", "share", "sounds", "bell"), true},
+	}
+	for _, tt := range tests {
+		if got := IsFile(tt.sound); got != tt.expected {
+			t.Errorf("IsFile(%q) = %v, expected %v", tt.sound, got, tt.expected)
+		}
+	}
+}
+
+func TestPlayerCommands_ThemeSound(t *testing.T) {
+	commands := playerCommands("message-new-instant")
+	if len(commands) != 1 || commands[0][0] != "canberra-gtk-play" || commands[0][2] != "message-new-instant" {
+		t.Errorf("Expected theme sounds to be played by canberra-gtk-play, got %v", commands)
+	}
+}
@@ -1,70 +1,252 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/clobrano/mcp-desktop-notification/internal/config"
+	"github.com/clobrano/mcp-desktop-notification/internal/history"
 	"github.com/clobrano/mcp-desktop-notification/internal/mcp"
 	"github.com/clobrano/mcp-desktop-notification/internal/notifier"
 )
 
+// configWatchInterval is how often the configuration file is checked for changes
+const configWatchInterval = 2 * time.Second
+
 func main() {
+	// Subcommands work on the configuration instead of running the server
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Parse command-line flags
 	configPath := flag.String("config", "", "Path to configuration file (default: platform-specific)")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
 	dryRun := flag.Bool("dry-run", false, "Dry run mode (log notifications without sending)")
+	transport := flag.String("transport", mcp.TransportStdio, "MCP transport: stdio, http (streamable HTTP), sse or unix (local daemon socket)")
+	listen := flag.String("listen", "", "Address to listen on for the http and sse transports (default: localhost:8787), or socket path for unix (default: $XDG_RUNTIME_DIR/mcp-poke.sock)")
+	connect := flag.Bool("connect", false, "Relay stdio to the local daemon socket (see -transport=unix) instead of running a server")
 	flag.Parse()
 
-	// Load configuration
-	var cfg *config.Config
-	var err error
-
-	if *configPath != "" {
-		cfg, err = config.LoadConfig(*configPath)
-	} else {
-		cfg, err = config.LoadDefaultConfig()
+	// Act as a thin stdio shim for the daemon; all the work happens there
+	if *connect {
+		socketPath := *listen
+		if socketPath == "" {
+			socketPath = config.GetSocketPath()
+		}
+		if err := mcp.Proxy(socketPath, os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("Proxy error: %v", err)
+		}
+		os.Exit(0)
 	}
 
+	// Load configuration
+	path := *configPath
+	if path == "" {
+		path = config.GetConfigPath()
+	}
+	layers := config.Layers(path)
+	cfg, err := loadConfig(layers, *verbose, *dryRun)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Override config with command-line flags
-	if *verbose {
-		cfg.Notification.Verbose = true
-	}
-	if *dryRun {
-		cfg.Notification.DryRun = true
-	}
-
 	// Log configuration if verbose
 	if cfg.Notification.Verbose {
 		log.Printf("[Main] Configuration loaded - DryRun: %v, Verbose: %v",
 			cfg.Notification.DryRun, cfg.Notification.Verbose)
 	}
 
-	// Create notifier
-	noti, err := notifier.NewNotifier(cfg)
+	noti, store, err := buildNotifier(cfg, nil, nil)
 	if err != nil {
 		log.Fatalf("Failed to create notifier: %v", err)
 	}
 
-	if cfg.Notification.Verbose {
-		log.Printf("[Main] Notifier created successfully")
-	}
-
 	// Create and start MCP server
 	server := mcp.NewServer(cfg, noti)
+	if store != nil {
+		server.SetHistory(store)
+	}
+
+	// Apply configuration changes without restarting the clients' sessions
+	reloader := &reloader{
+		layers:  layers,
+		verbose: *verbose,
+		dryRun:  *dryRun,
+		server:  server,
+		config:  cfg,
+		store:   store,
+	}
+	go reloader.run()
 
 	if cfg.Notification.Verbose {
 		log.Printf("[Main] Starting MCP server...")
 	}
 
-	if err := server.Start(); err != nil {
+	if err := server.Serve(*transport, *listen); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 
 	os.Exit(0)
 }
+
+// loadConfig loads the configuration files of layers and the environment, then
+// applies the command-line overrides
+func loadConfig(layers []config.Layer, verbose, dryRun bool) (*config.Config, error) {
+	cfg, err := config.LoadLayers(layers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Override config with command-line flags
+	if verbose {
+		cfg.Notification.Verbose = true
+		cfg.SetSource([]string{"notification", "verbose"}, "-verbose flag")
+	}
+	if dryRun {
+		cfg.Notification.DryRun = true
+		cfg.SetSource([]string{"notification", "dry_run"}, "-dry-run flag")
+	}
+	return cfg, nil
+}
+
+// buildNotifier creates the notifier chain for cfg. The history store is
+// reused when the previous configuration opened the same one
+func buildNotifier(cfg, previous *config.Config, previousStore *history.Store) (notifier.Notifier, *history.Store, error) {
+	// Create notifier
+	noti, err := notifier.NewNotifier(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.Notification.Verbose {
+		log.Printf("[Main] Notifier created successfully")
+	}
+
+	// Collapse bursts into digests
+	if cfg.Notification.Batch.Enabled {
+		noti = notifier.NewBatchNotifier(cfg, noti)
+	}
+
+	// Hold back less urgent notifications during quiet hours. The digest sent
+	// when they end is not subject to rate limiting
+	if cfg.Notification.Schedule.Enabled {
+		noti = notifier.NewScheduleNotifier(cfg, noti)
+	}
+
+	// Drop duplicates and floods before they reach the desktop
+	if cfg.Notification.RateLimit.Enabled {
+		noti = notifier.NewRateLimitNotifier(cfg, noti)
+	}
+
+	// Record every notification in the history store
+	var store *history.Store
+	if cfg.Notification.History.Enabled {
+		if previousStore != nil && previous.HistoryPath() == cfg.HistoryPath() &&
+			previous.Notification.History == cfg.Notification.History {
+			store = previousStore
+		} else {
+			store, err = history.Open(cfg.HistoryPath(), cfg.Notification.History.MaxEntries,
+				time.Duration(cfg.Notification.History.MaxAgeDays)*24*time.Hour)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open notification history: %w", err)
+			}
+		}
+		noti = notifier.NewHistoryNotifier(cfg, noti, store)
+
+		if cfg.Notification.Verbose {
+			log.Printf("[Main] Recording notification history in %s", store.Path())
+		}
+	}
+
+	// Keep an auditable log of every notification and how it was delivered
+	if cfg.Notification.LogFile.Enabled {
+		fileNotifier := notifier.NewFileNotifier(cfg, cfg.Notification.LogFile.FileConfig, noti)
+		noti = fileNotifier
+
+		if cfg.Notification.Verbose {
+			log.Printf("[Main] Logging notifications to %s", fileNotifier.Path())
+		}
+	}
+
+	// Digests go through the whole chain so they are recorded like the rest
+	notifier.RouteDigests(noti)
+
+	return noti, store, nil
+}
+
+// reloader reloads the configuration when one of its files changes or on SIGHUP
+type reloader struct {
+	layers  []config.Layer
+	verbose bool
+	dryRun  bool
+	server  *mcp.Server
+	// config and store are the ones in use, only accessed by run
+	config *config.Config
+	store  *history.Store
+}
+
+// run waits for configuration changes and applies them one at a time
+func (r *reloader) run() {
+	reasons := make(chan string, 1)
+	trigger := func(reason string) {
+		// A pending reload reads the file again anyway
+		select {
+		case reasons <- reason:
+		default:
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			trigger("SIGHUP")
+		}
+	}()
+	for _, layer := range r.layers {
+		go config.Watch(context.Background(), layer.Path, configWatchInterval, func() {
+			trigger(layer.Path + " changed")
+		})
+	}
+
+	for reason := range reasons {
+		r.reload(reason)
+	}
+}
+
+// reload loads and validates the configuration, keeping the current one when
+// it is invalid
+func (r *reloader) reload(reason string) {
+	cfg, err := loadConfig(r.layers, r.verbose, r.dryRun)
+	if err != nil {
+		log.Printf("[Main] Not reloading configuration (%s), keeping the current one: %v", reason, err)
+		return
+	}
+
+	noti, store, err := buildNotifier(cfg, r.config, r.store)
+	if err != nil {
+		log.Printf("[Main] Not reloading configuration (%s), keeping the current one: %v", reason, err)
+		return
+	}
+
+	previous, err := r.server.Reload(cfg, noti, store)
+	if err != nil {
+		log.Printf("[Main] Failed to update the tools after reloading configuration: %v", err)
+	}
+	r.config, r.store = cfg, store
+	log.Printf("[Main] Configuration reloaded (%s)", reason)
+
+	// Deliver what the previous notifier still holds, e.g. a pending digest
+	go func() {
+		if err := notifier.Flush(context.Background(), previous); err != nil {
+			log.Printf("[Main] Failed to flush the previous notifier: %v", err)
+		}
+	}()
+}